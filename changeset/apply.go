@@ -0,0 +1,412 @@
+package changeset
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kpawlik/om"
+	so "github.com/kpawlik/superobject"
+)
+
+// ErrConflict is returned when the target definition differs from the old value of a change
+var ErrConflict = errors.New("target differs from changeset")
+
+// ConflictError reports an attribute of the target field which has neither the old nor the new value of the change.
+// errors.Is(err, ErrConflict) is true for this error.
+type ConflictError struct {
+	Path    string
+	Feature string
+	Field   string
+	// Attribute is the path of the attribute, e.g. "enum_values[3].value"
+	Attribute string
+	// Expected is the old value of the change, Found the value in the target. nil if the value does not exist.
+	Expected json.RawMessage
+	Found    json.RawMessage
+}
+
+func (e *ConflictError) Error() string {
+	msg := fmt.Sprintf("%s: field %s: %s: %v: expected %s, found %s",
+		e.Feature, e.Field, e.Attribute, ErrConflict, formatRaw(e.Expected), formatRaw(e.Found))
+	if e.Path == "" {
+		return msg
+	}
+	return e.Path + ": " + msg
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+func formatRaw(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return "none"
+	}
+	return string(raw)
+}
+
+// Result is a summary of changes applied to one feature
+type Result struct {
+	Feature string `json:"feature"`
+	// State is added or removed when the whole feature was added or removed, empty otherwise
+	State so.DiffState `json:"state,omitempty"`
+	// Added, Removed and Modified are names of fields
+	Added    []string `json:"added,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+	Modified []string `json:"modified,omitempty"`
+	// Unchanged are fields whose changes were already in the target
+	Unchanged []string `json:"unchanged,omitempty"`
+	// Skipped are changes which were not applied, because of errors or because only
+	// fields and whole features are applied, e.g. "groups/main: changed"
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// Changed returns true if the target definition was changed
+func (r *Result) Changed() bool {
+	return r.State != "" || len(r.Added) > 0 || len(r.Removed) > 0 || len(r.Modified) > 0
+}
+
+// String returns the summary in one line, e.g. "eo_cable: added 2, removed 1, modified 3"
+func (r *Result) String() string {
+	if r.State != "" {
+		return fmt.Sprintf("%s: feature %s", r.Feature, r.State)
+	}
+	parts := []string{}
+	for _, count := range []struct {
+		name  string
+		items []string
+	}{{"added", r.Added}, {"removed", r.Removed}, {"modified", r.Modified}, {"unchanged", r.Unchanged}, {"skipped", r.Skipped}} {
+		if len(count.items) > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", count.name, len(count.items)))
+		}
+	}
+	if len(parts) == 0 {
+		return r.Feature + ": no changes"
+	}
+	return r.Feature + ": " + strings.Join(parts, ", ")
+}
+
+// String returns the change as "<section>/<name>: <state>"
+func (c Change) String() string {
+	if c.Name == "" {
+		return fmt.Sprintf("%s: %s", c.Section, c.State)
+	}
+	return fmt.Sprintf("%s/%s: %s", c.Section, c.Name, c.State)
+}
+
+// Of returns changes of the feature, in order of the changeset
+func (cs *Changeset) Of(feature string) (changes []Change) {
+	for _, change := range cs.Changes {
+		if change.Feature == feature {
+			changes = append(changes, change)
+		}
+	}
+	return
+}
+
+// ApplyFeature applies changes of fields to the target definition of the feature.
+// Added fields are copied from the source definition with superobject.CopyField and CopyFieldGroups,
+// so they keep the source order and groups. source may be nil when no fields are added.
+// Removed fields are removed with references to them by superobject.FeatureDef.RemoveFieldCascade.
+// Changed attributes are applied only when the target has the old value,
+// otherwise *ConflictError is recorded and the field is not changed.
+// Changes of other sections and of whole features are skipped.
+func ApplyFeature(target, source *so.FeatureDef, changes []Change, diag *so.Diagnostics) *Result {
+	result := &Result{Feature: target.Name()}
+	for _, change := range changes {
+		if !change.IsField() {
+			result.Skipped = append(result.Skipped, change.String())
+			continue
+		}
+		var (
+			applied bool
+			err     error
+		)
+		switch change.State {
+		case Added:
+			applied, err = addField(target, source, change.Name)
+		case Removed:
+			applied, err = removeField(target, change.Name)
+		case Changed:
+			applied, err = changeField(target, change)
+		default:
+			err = fmt.Errorf("%s: field %s: unknown change state %q", change.Feature, change.Name, change.State)
+		}
+		switch {
+		case diag.Error(err):
+			result.Skipped = append(result.Skipped, change.String())
+			continue
+		case !applied:
+			result.Unchanged = append(result.Unchanged, change.Name)
+			continue
+		}
+		switch change.State {
+		case Added:
+			result.Added = append(result.Added, change.Name)
+		case Removed:
+			result.Removed = append(result.Removed, change.Name)
+		default:
+			result.Modified = append(result.Modified, change.Name)
+		}
+		if change.TypeChange == so.TypeChangeLossy.String() {
+			diag.Warningf("%s: field %s: lossy type change", change.Feature, change.Name)
+		}
+	}
+	return result
+}
+
+// addField copies the field and its groups from the source, false if the target already has the same field
+func addField(target, source *so.FeatureDef, name string) (bool, error) {
+	if source == nil {
+		return false, fmt.Errorf("%s: field %s: no source definition to copy added field from", target.Name(), name)
+	}
+	if field := target.Field(name); field != nil {
+		sourceField := source.Field(name)
+		if sourceField != nil && len(so.DeepDiff(field.Raw(), sourceField.Raw())) == 0 {
+			return false, nil
+		}
+		return false, fmt.Errorf("%s: field %s: added field already exists with other attributes", target.Name(), name)
+	}
+	if err := so.CopyField(target, source, name); err != nil {
+		return false, err
+	}
+	so.CopyFieldGroups(target, source, name)
+	return true, nil
+}
+
+// removeField removes the field and references to it, false if it does not exist
+func removeField(target *so.FeatureDef, name string) (bool, error) {
+	if !target.HasField(name) {
+		return false, nil
+	}
+	_, err := target.RemoveFieldCascade(name)
+	return err == nil, err
+}
+
+// changeField applies changed attributes to the field. All attributes are checked before the field is changed,
+// so the field is changed completely or not at all. Returns false if all attributes already have new values.
+func changeField(target *so.FeatureDef, change Change) (bool, error) {
+	field := target.Field(change.Name)
+	if field == nil {
+		return false, &so.FieldNotFoundError{Path: target.Path, Feature: target.Name(), Field: change.Name}
+	}
+	if len(change.Attributes) == 0 {
+		return false, fmt.Errorf("%s: field %s: changed field has no attribute values", target.Name(), change.Name)
+	}
+	type update struct {
+		path  []pathStep
+		value any
+		state so.DiffState
+	}
+	var updates, removes []update
+	for _, attr := range change.Attributes {
+		path, err := parsePath(attr.Path)
+		if err != nil {
+			return false, fmt.Errorf("%s: field %s: %w", target.Name(), change.Name, err)
+		}
+		oldValue, err := Value(attr.Old)
+		if err != nil {
+			return false, err
+		}
+		newValue, err := Value(attr.New)
+		if err != nil {
+			return false, err
+		}
+		current, exists := getPath(field.Raw(), path)
+		switch {
+		case attr.State == Removed && !exists,
+			attr.State != Removed && exists && equalValues(current, newValue):
+			// already applied
+			continue
+		case attr.State == Added && exists,
+			attr.State != Added && (!exists || !equalValues(current, oldValue)):
+			found, _ := so.MarshalValue(current)
+			if !exists {
+				found = nil
+			}
+			return false, &ConflictError{Path: target.Path, Feature: target.Name(), Field: change.Name,
+				Attribute: attr.Path, Expected: attr.Old, Found: found}
+		}
+		if attr.State == Removed {
+			removes = append(removes, update{path: path, state: attr.State})
+		} else {
+			updates = append(updates, update{path: path, value: newValue, state: attr.State})
+		}
+	}
+	if len(updates) == 0 && len(removes) == 0 {
+		return false, nil
+	}
+	// items are added to the end of lists in ascending order and removed from the end in descending order
+	for _, u := range updates {
+		if _, err := setPath(field.Raw(), u.path, u.value); err != nil {
+			return false, fmt.Errorf("%s: field %s: %w", target.Name(), change.Name, err)
+		}
+	}
+	for i := len(removes) - 1; i >= 0; i-- {
+		if _, err := deletePath(field.Raw(), removes[i].path); err != nil {
+			return false, fmt.Errorf("%s: field %s: %w", target.Name(), change.Name, err)
+		}
+	}
+	return true, nil
+}
+
+func equalValues(value1, value2 any) bool {
+	return len(so.DeepDiff(value1, value2)) == 0
+}
+
+// pathStep is a key of an object or an index of a list, index is -1 for keys
+type pathStep struct {
+	key   string
+	index int
+}
+
+func (s pathStep) String() string {
+	if s.index < 0 {
+		return s.key
+	}
+	return fmt.Sprintf("[%d]", s.index)
+}
+
+// parsePath parses attribute path of superobject.DeepDiff, e.g. "enum_values[3].value"
+func parsePath(path string) (steps []pathStep, err error) {
+	if path == "" {
+		return nil, errors.New("empty attribute path")
+	}
+	for _, part := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key == "" && (len(steps) == 0 || rest == "") {
+			return nil, fmt.Errorf("malformed attribute path %q", path)
+		}
+		if key != "" {
+			steps = append(steps, pathStep{key: key, index: -1})
+		}
+		for rest != "" {
+			var index string
+			var ok bool
+			if index, rest, ok = strings.Cut(rest, "]"); !ok {
+				return nil, fmt.Errorf("malformed attribute path %q", path)
+			}
+			i, err := strconv.Atoi(index)
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("malformed attribute path %q", path)
+			}
+			steps = append(steps, pathStep{index: i})
+			if rest != "" {
+				if rest[0] != '[' {
+					return nil, fmt.Errorf("malformed attribute path %q", path)
+				}
+				rest = rest[1:]
+			}
+		}
+	}
+	return
+}
+
+// asList returns the value as a list, lists of strings created in memory are converted
+func asList(value any) ([]any, bool) {
+	switch v := value.(type) {
+	case []any:
+		return v, true
+	case []string:
+		list := make([]any, len(v))
+		for i, s := range v {
+			list[i] = s
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+// getPath returns the value at the path, false if it does not exist
+func getPath(value any, path []pathStep) (any, bool) {
+	for _, step := range path {
+		if m, ok := value.(*om.OrderedMap); ok && step.index < 0 {
+			if value, ok = m.Map[step.key]; !ok {
+				return nil, false
+			}
+			continue
+		}
+		list, ok := asList(value)
+		if !ok || step.index < 0 || step.index >= len(list) {
+			return nil, false
+		}
+		value = list[step.index]
+	}
+	return value, true
+}
+
+// setPath sets the value at the path and returns the updated value. Missing key is appended to its object,
+// list item can be set at the index of the list length to append it.
+func setPath(value any, path []pathStep, newValue any) (any, error) {
+	if len(path) == 0 {
+		return newValue, nil
+	}
+	step := path[0]
+	if m, ok := value.(*om.OrderedMap); ok && step.index < 0 {
+		child, exists := m.Map[step.key]
+		if !exists && len(path) > 1 {
+			return nil, fmt.Errorf("attribute %s does not exist", step)
+		}
+		child, err := setPath(child, path[1:], newValue)
+		if err != nil {
+			return nil, err
+		}
+		m.Set(step.key, child)
+		return m, nil
+	}
+	list, ok := asList(value)
+	switch {
+	case !ok || step.index < 0:
+		return nil, fmt.Errorf("attribute %s does not exist", step)
+	case step.index == len(list) && len(path) == 1:
+		return append(list, newValue), nil
+	case step.index >= len(list):
+		return nil, fmt.Errorf("attribute %s does not exist", step)
+	}
+	child, err := setPath(list[step.index], path[1:], newValue)
+	if err != nil {
+		return nil, err
+	}
+	list[step.index] = child
+	return list, nil
+}
+
+// deletePath removes the value at the path and returns the updated value
+func deletePath(value any, path []pathStep) (any, error) {
+	step := path[0]
+	if m, ok := value.(*om.OrderedMap); ok && step.index < 0 {
+		child, exists := m.Map[step.key]
+		switch {
+		case !exists:
+			return m, nil
+		case len(path) == 1:
+			delete(m.Map, step.key)
+			m.Keys = slices.DeleteFunc(m.Keys, func(key string) bool { return key == step.key })
+			return m, nil
+		}
+		child, err := deletePath(child, path[1:])
+		if err != nil {
+			return nil, err
+		}
+		m.Set(step.key, child)
+		return m, nil
+	}
+	list, ok := asList(value)
+	switch {
+	case !ok || step.index < 0:
+		return nil, fmt.Errorf("attribute %s does not exist", step)
+	case step.index >= len(list):
+		return list, nil
+	case len(path) == 1:
+		return slices.Delete(list, step.index, step.index+1), nil
+	}
+	child, err := deletePath(list[step.index], path[1:])
+	if err != nil {
+		return nil, err
+	}
+	list[step.index] = child
+	return list, nil
+}
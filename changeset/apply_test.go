@@ -0,0 +1,115 @@
+package changeset
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	so "github.com/kpawlik/superobject"
+)
+
+func TestApplyFeatureChanged(t *testing.T) {
+	const def = `{"name": "eo_cable", "fields": [{"name": "voltage", "type": %s, "unit": "kV", "enum_values": [{"value": "a"}, {"value": "b"}]}]}`
+	typeChange := Attribute{Path: "type", State: Changed, Old: json.RawMessage(`"string(100)"`), New: json.RawMessage(`"string(50)"`)}
+	tests := []struct {
+		name       string
+		targetType string
+		attributes []Attribute
+		// want is the field in the target after apply
+		want     string
+		modified bool
+		conflict *ConflictError
+	}{
+		{
+			name:       "apply",
+			targetType: `"string(100)"`,
+			attributes: []Attribute{typeChange, {Path: "unit", State: Removed, Old: json.RawMessage(`"kV"`)}},
+			want:       `{"name": "voltage", "type": "string(50)", "enum_values": [{"value": "a"}, {"value": "b"}]}`,
+			modified:   true,
+		},
+		{
+			name:       "list items",
+			targetType: `"string(100)"`,
+			attributes: []Attribute{
+				{Path: "enum_values[1].value", State: Changed, Old: json.RawMessage(`"b"`), New: json.RawMessage(`"c"`)},
+				{Path: "enum_values[2]", State: Added, New: json.RawMessage(`{"value": "d"}`)},
+			},
+			want:     `{"name": "voltage", "type": "string(100)", "unit": "kV", "enum_values": [{"value": "a"}, {"value": "c"}, {"value": "d"}]}`,
+			modified: true,
+		},
+		{
+			name:       "already applied",
+			targetType: `"string(50)"`,
+			attributes: []Attribute{typeChange, {Path: "display_unit", State: Removed, Old: json.RawMessage(`"V"`)}},
+			want:       `{"name": "voltage", "type": "string(50)", "unit": "kV", "enum_values": [{"value": "a"}, {"value": "b"}]}`,
+		},
+		{
+			name:       "diverged",
+			targetType: `"string(80)"`,
+			attributes: []Attribute{typeChange},
+			want:       `{"name": "voltage", "type": "string(80)", "unit": "kV", "enum_values": [{"value": "a"}, {"value": "b"}]}`,
+			conflict:   &ConflictError{Attribute: "type", Expected: json.RawMessage(`"string(100)"`), Found: json.RawMessage(`"string(80)"`)},
+		},
+		{
+			name:       "diverged after applicable attribute",
+			targetType: `"string(100)"`,
+			attributes: []Attribute{typeChange, {Path: "unit", State: Changed, Old: json.RawMessage(`"V"`), New: json.RawMessage(`"mV"`)}},
+			want:       `{"name": "voltage", "type": "string(100)", "unit": "kV", "enum_values": [{"value": "a"}, {"value": "b"}]}`,
+			conflict:   &ConflictError{Attribute: "unit", Expected: json.RawMessage(`"V"`), Found: json.RawMessage(`"kV"`)},
+		},
+		{
+			name:       "added attribute exists",
+			targetType: `"string(100)"`,
+			attributes: []Attribute{{Path: "unit", State: Added, New: json.RawMessage(`"V"`)}},
+			want:       `{"name": "voltage", "type": "string(100)", "unit": "kV", "enum_values": [{"value": "a"}, {"value": "b"}]}`,
+			conflict:   &ConflictError{Attribute: "unit", Found: json.RawMessage(`"kV"`)},
+		},
+		{
+			name:       "changed attribute missing",
+			targetType: `"string(100)"`,
+			attributes: []Attribute{{Path: "display_unit", State: Changed, Old: json.RawMessage(`"V"`), New: json.RawMessage(`"mV"`)}},
+			want:       `{"name": "voltage", "type": "string(100)", "unit": "kV", "enum_values": [{"value": "a"}, {"value": "b"}]}`,
+			conflict:   &ConflictError{Attribute: "display_unit", Expected: json.RawMessage(`"V"`)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := so.ParseFeatureDef([]byte(fmt.Sprintf(def, tt.targetType)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			change := Change{Feature: "eo_cable", Section: so.SectionFields, Name: "voltage", State: Changed, Attributes: tt.attributes}
+			diag := &so.Diagnostics{}
+			result := ApplyFeature(target, nil, []Change{change}, diag)
+			if modified := len(result.Modified) > 0; modified != tt.modified {
+				t.Errorf("modified = %v, want %v, result %v", modified, tt.modified, result)
+			}
+			items := diag.Items()
+			if tt.conflict == nil {
+				if len(items) > 0 {
+					t.Errorf("unexpected diagnostics: %v", items)
+				}
+			} else {
+				var conflict *ConflictError
+				if len(items) != 1 || !errors.As(items[0].Err, &conflict) || !errors.Is(items[0].Err, ErrConflict) {
+					t.Fatalf("diagnostics = %v, want *ConflictError", items)
+				}
+				if conflict.Field != "voltage" || conflict.Attribute != tt.conflict.Attribute ||
+					string(conflict.Expected) != string(tt.conflict.Expected) || string(conflict.Found) != string(tt.conflict.Found) {
+					t.Errorf("conflict = %+v, want %+v", conflict, tt.conflict)
+				}
+				if len(result.Skipped) != 1 {
+					t.Errorf("skipped = %v, want the change", result.Skipped)
+				}
+			}
+			want, err := so.ParseFeatureDef([]byte(`{"fields": [` + tt.want + `]}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diffs := so.DeepDiff(target.Field("voltage").Raw(), want.Field("voltage").Raw()); len(diffs) > 0 {
+				t.Errorf("field differs from expected: %v", diffs)
+			}
+		})
+	}
+}
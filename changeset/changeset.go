@@ -0,0 +1,155 @@
+// Package changeset describes changes between two versions of feature definitions.
+// Changesets are written by compare-feature-defs and read by add-fields, remove-fields,
+// check-used-fields and apply, as versioned JSON or as CSV with a header.
+package changeset
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/kpawlik/om"
+	so "github.com/kpawlik/superobject"
+)
+
+// Version is the version of the changeset format written by this package
+const Version = 1
+
+// State of a change, see superobject.DiffState
+const (
+	Added   = so.DiffAdded
+	Removed = so.DiffRemoved
+	Changed = so.DiffChanged
+)
+
+// Changeset is a list of changes from the first version of definitions to the second one
+type Changeset struct {
+	Version int `json:"version"`
+	// Name1 and Name2 are names of compared versions, e.g. "Dir 1" and "Dir 2"
+	Name1   string   `json:"name1,omitempty"`
+	Name2   string   `json:"name2,omitempty"`
+	Changes []Change `json:"changes"`
+}
+
+// Change is one added, removed or changed feature, field or item of other section
+type Change struct {
+	Feature string `json:"feature"`
+	// Section is superobject.SectionFeature for whole features, superobject.SectionFields for fields,
+	// or other top-level key of the definition, e.g. "groups" or "title"
+	Section string `json:"section"`
+	// Name of the field or item, empty for whole features and sections
+	Name  string       `json:"name,omitempty"`
+	State so.DiffState `json:"state"`
+	// TypeChange is safe, widening or lossy when type of a field changed
+	TypeChange string `json:"type_change,omitempty"`
+	// Attributes are changed attributes of changed items
+	Attributes []Attribute `json:"attributes,omitempty"`
+}
+
+// Attribute is a change of one attribute, values are JSON
+type Attribute struct {
+	// Path of the attribute, e.g. "unit" or "enum_values[3].value"
+	Path  string          `json:"path"`
+	State so.DiffState    `json:"state"`
+	Old   json.RawMessage `json:"old,omitempty"`
+	New   json.RawMessage `json:"new,omitempty"`
+}
+
+// New returns an empty changeset of the current version
+func New(name1, name2 string) *Changeset {
+	return &Changeset{Version: Version, Name1: name1, Name2: name2, Changes: []Change{}}
+}
+
+// IsField returns true if the change is a change of a field
+func (c Change) IsField() bool {
+	return c.Section == so.SectionFields
+}
+
+// IsFeature returns true if the change is a change of a whole feature
+func (c Change) IsFeature() bool {
+	return c.Section == so.SectionFeature
+}
+
+// Features returns names of features, in order of changes
+func (cs *Changeset) Features() (features []string) {
+	for _, change := range cs.Changes {
+		if !slices.Contains(features, change.Feature) {
+			features = append(features, change.Feature)
+		}
+	}
+	return
+}
+
+// Fields returns names of fields with the given state for each feature, in order of changes
+func (cs *Changeset) Fields(state so.DiffState) map[string][]string {
+	fields := map[string][]string{}
+	for _, change := range cs.Changes {
+		if change.IsField() && change.State == state {
+			fields[change.Feature] = append(fields[change.Feature], change.Name)
+		}
+	}
+	return fields
+}
+
+// Compare returns changes between two versions of the feature definition.
+// def1 nil means the feature was added, def2 nil that it was removed.
+func Compare(def1, def2 *so.FeatureDef) (changes []Change) {
+	switch {
+	case def1 == nil && def2 == nil:
+		return nil
+	case def1 == nil:
+		return []Change{{Feature: def2.Name(), Section: so.SectionFeature, State: Added}}
+	case def2 == nil:
+		return []Change{{Feature: def1.Name(), Section: so.SectionFeature, State: Removed}}
+	}
+	for _, diff := range so.CompareDefs(def1, def2) {
+		changes = append(changes, FromDiff(def1.Name(), diff))
+	}
+	return
+}
+
+// FromDiff returns change of the feature from the difference found by superobject.CompareDefs
+func FromDiff(feature string, diff so.ItemDiff) Change {
+	change := Change{Feature: feature, Section: diff.Section, Name: diff.Name, State: diff.State}
+	for _, attr := range diff.Changes {
+		change.Attributes = append(change.Attributes, Attribute{
+			Path:  attr.Path,
+			State: attr.State,
+			Old:   rawValue(attr.Old, attr.State != Added),
+			New:   rawValue(attr.New, attr.State != Removed),
+		})
+		if change.IsField() && attr.Path == "type" && attr.State == Changed {
+			change.TypeChange = so.CompareTypeStrings(so.FormatValue(attr.Old), so.FormatValue(attr.New)).String()
+		}
+	}
+	return change
+}
+
+// rawValue returns JSON of the value, nil if the value does not exist
+func rawValue(value any, exists bool) json.RawMessage {
+	if !exists {
+		return nil
+	}
+	data, err := so.MarshalValue(value)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// Value returns the value parsed in the same way as values of feature definitions, objects are ordered maps
+func Value(raw json.RawMessage) (value any, err error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	wrapper := om.NewOrderedMap()
+	var buf bytes.Buffer
+	buf.WriteString(`{"value":`)
+	buf.Write(raw)
+	buf.WriteString(`}`)
+	if err = wrapper.UnmarshalJSON(buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to parse value %s: %w", raw, err)
+	}
+	return wrapper.Map["value"], nil
+}
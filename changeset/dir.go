@@ -0,0 +1,126 @@
+package changeset
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	so "github.com/kpawlik/superobject"
+)
+
+// ApplyOptions control how ApplyDir reads and writes def files
+type ApplyOptions struct {
+	// TargetDir is the dir of defs the changes are applied to
+	TargetDir string
+	// SourceDir is the dir of defs added fields and features are copied from, may be empty when nothing is added
+	SourceDir string
+	// DryRun applies changes in memory only, no files are written or removed
+	DryRun bool
+	// Backup of modified and removed def files
+	Backup so.BackupMode
+}
+
+// ApplyDir applies changes of the changeset to <feature>.def files of opts.TargetDir, in order of features of the changeset.
+// Added features are copied from opts.SourceDir, removed ones are removed from the target dir.
+// Changes of fields are applied with ApplyFeature. Features which can not be read or applied are recorded in diag
+// and have no result. Applying the same changeset again does not change the files.
+func ApplyDir(cs *Changeset, opts ApplyOptions, diag *so.Diagnostics) (results []*Result) {
+	for _, feature := range cs.Features() {
+		if result := opts.apply(feature, cs.Of(feature), diag); result != nil {
+			results = append(results, result)
+		}
+	}
+	return
+}
+
+func (opts ApplyOptions) targetPath(feature string) string {
+	return filepath.Join(opts.TargetDir, feature+".def")
+}
+
+func (opts ApplyOptions) sourcePath(feature string) string {
+	return filepath.Join(opts.SourceDir, feature+".def")
+}
+
+// apply applies changes of the feature, nil if the target def can not be read
+func (opts ApplyOptions) apply(feature string, changes []Change, diag *so.Diagnostics) *Result {
+	for _, change := range changes {
+		if change.IsFeature() {
+			return opts.applyFeature(feature, change.State, diag)
+		}
+	}
+	targetPath := opts.targetPath(feature)
+	target, err := so.ReadDefFile(targetPath)
+	if diag.Error(err) {
+		return nil
+	}
+	var source *so.FeatureDef
+	if opts.SourceDir != "" && hasAddedFields(changes) {
+		if source, err = so.ReadDefFile(opts.sourcePath(feature)); diag.Error(err) {
+			source = nil
+		}
+	}
+	result := ApplyFeature(target, source, changes, diag)
+	if result.Changed() && !opts.DryRun {
+		diag.Error(so.WriteDefFile(targetPath, target, so.WriteOptions{Backup: opts.Backup}))
+	}
+	return result
+}
+
+func hasAddedFields(changes []Change) bool {
+	for _, change := range changes {
+		if change.IsField() && change.State == Added {
+			return true
+		}
+	}
+	return false
+}
+
+// applyFeature copies added feature from the source dir or removes the removed one from the target dir
+func (opts ApplyOptions) applyFeature(feature string, state so.DiffState, diag *so.Diagnostics) *Result {
+	result := &Result{Feature: feature}
+	targetPath := opts.targetPath(feature)
+	_, err := os.Stat(targetPath)
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		diag.Error(err)
+		return nil
+	}
+	switch state {
+	case Added:
+		if opts.SourceDir == "" {
+			diag.Errorf("%s: source dir is required to add feature", feature)
+			return nil
+		}
+		source, err := so.ReadDefFile(opts.sourcePath(feature))
+		if diag.Error(err) {
+			return nil
+		}
+		if exists {
+			target, err := so.ReadDefFile(targetPath)
+			if diag.Error(err) {
+				return nil
+			}
+			if len(so.CompareDefs(target, source)) > 0 {
+				diag.Errorf("%s: added feature already exists in %s with other definition", feature, targetPath)
+			}
+			return result
+		}
+		result.State = Added
+		if !opts.DryRun {
+			diag.Error(so.WriteDefFile(targetPath, source, so.WriteOptions{}))
+		}
+	case Removed:
+		if !exists {
+			return result
+		}
+		result.State = Removed
+		if !opts.DryRun {
+			diag.Error(so.RemoveFile(targetPath, so.WriteOptions{Backup: opts.Backup}))
+		}
+	default:
+		diag.Errorf("%s: unknown feature change state %q", feature, state)
+		return nil
+	}
+	return result
+}
@@ -0,0 +1,219 @@
+package changeset
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	so "github.com/kpawlik/superobject"
+)
+
+const (
+	sourceCable = `{"name": "eo_cable", "fields": [{"name": "id", "type": "integer"}, {"name": "label", "type": "string(10)"}, {"name": "voltage", "type": "double"}]}`
+	targetCable = `{"name": "eo_cable", "fields": [{"name": "id", "type": "integer"}, {"name": "voltage", "type": "double"}, {"name": "status", "type": "string(10)"}]}`
+)
+
+// applyDirs returns source and target dirs with def files
+func applyDirs(t *testing.T, target map[string]string) (sourceDir, targetDir string) {
+	t.Helper()
+	sourceDir, targetDir = t.TempDir(), t.TempDir()
+	writeDefs(t, sourceDir, map[string]string{
+		"eo_cable": sourceCable,
+		"eo_duct":  `{"name": "eo_duct", "fields": [{"name": "id", "type": "integer"}]}`,
+		"eo_pole":  `{"name": "eo_pole", "fields": [{"name": "id", "type": "integer"}]}`,
+	})
+	writeDefs(t, targetDir, target)
+	return
+}
+
+func writeDefs(t *testing.T, dir string, defs map[string]string) {
+	t.Helper()
+	for name, data := range defs {
+		if err := os.WriteFile(filepath.Join(dir, name+".def"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readDefs returns content of def files in the dir by feature name
+func readDefs(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.def"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs := map[string]string{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defs[strings.TrimSuffix(filepath.Base(path), ".def")] = string(data)
+	}
+	return defs
+}
+
+func resultStrings(results []*Result) (res []string) {
+	for _, result := range results {
+		res = append(res, result.String())
+	}
+	return
+}
+
+func errorStrings(diag *so.Diagnostics) (errs []string) {
+	for _, item := range diag.Items() {
+		if item.Severity == so.SeverityError {
+			errs = append(errs, item.Err.Error())
+		}
+	}
+	return
+}
+
+func TestApplyDir(t *testing.T) {
+	cs := &Changeset{Version: Version, Changes: []Change{
+		{Feature: "eo_duct", Section: so.SectionFeature, State: Added},
+		{Feature: "eo_manhole", Section: so.SectionFeature, State: Removed},
+		{Feature: "eo_cable", Section: so.SectionFields, Name: "label", State: Added},
+		{Feature: "eo_cable", Section: so.SectionFields, Name: "status", State: Removed},
+	}}
+	sourceDir, targetDir := applyDirs(t, map[string]string{
+		"eo_cable":   targetCable,
+		"eo_manhole": `{"name": "eo_manhole", "fields": []}`,
+	})
+	opts := ApplyOptions{TargetDir: targetDir, SourceDir: sourceDir}
+	var diag so.Diagnostics
+	results := ApplyDir(cs, opts, &diag)
+	want := []string{"eo_duct: feature added", "eo_manhole: feature removed", "eo_cable: added 1, removed 1"}
+	if got := resultStrings(results); !slices.Equal(got, want) {
+		t.Errorf("results = %q, want %q", got, want)
+	}
+	if errs := errorStrings(&diag); errs != nil {
+		t.Fatalf("errors %q", errs)
+	}
+	defs := readDefs(t, targetDir)
+	if _, ok := defs["eo_duct"]; !ok {
+		t.Error("added feature eo_duct was not copied")
+	}
+	if _, ok := defs["eo_manhole"]; ok {
+		t.Error("removed feature eo_manhole was not removed")
+	}
+	cable, err := so.ParseFeatureDef([]byte(defs["eo_cable"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cable.FieldNames(), []string{"id", "label", "voltage"}; !slices.Equal(got, want) {
+		t.Errorf("eo_cable fields = %v, want %v", got, want)
+	}
+
+	// applying the same changeset again changes nothing
+	results = ApplyDir(cs, opts, &diag)
+	want = []string{"eo_duct: no changes", "eo_manhole: no changes", "eo_cable: unchanged 2"}
+	if got := resultStrings(results); !slices.Equal(got, want) {
+		t.Errorf("results of second apply = %q, want %q", got, want)
+	}
+	if errs := errorStrings(&diag); errs != nil {
+		t.Fatalf("errors of second apply %q", errs)
+	}
+	if got := readDefs(t, targetDir); !maps.Equal(got, defs) {
+		t.Errorf("second apply changed target defs:\n%v\nwant\n%v", got, defs)
+	}
+}
+
+func TestApplyDirDryRun(t *testing.T) {
+	cs := &Changeset{Version: Version, Changes: []Change{
+		{Feature: "eo_duct", Section: so.SectionFeature, State: Added},
+		{Feature: "eo_manhole", Section: so.SectionFeature, State: Removed},
+		{Feature: "eo_cable", Section: so.SectionFields, Name: "label", State: Added},
+	}}
+	sourceDir, targetDir := applyDirs(t, map[string]string{
+		"eo_cable":   targetCable,
+		"eo_manhole": `{"name": "eo_manhole", "fields": []}`,
+	})
+	before := readDefs(t, targetDir)
+	var diag so.Diagnostics
+	results := ApplyDir(cs, ApplyOptions{TargetDir: targetDir, SourceDir: sourceDir, DryRun: true}, &diag)
+	want := []string{"eo_duct: feature added", "eo_manhole: feature removed", "eo_cable: added 1"}
+	if got := resultStrings(results); !slices.Equal(got, want) {
+		t.Errorf("results = %q, want %q", got, want)
+	}
+	if errs := errorStrings(&diag); errs != nil {
+		t.Fatalf("errors %q", errs)
+	}
+	if got := readDefs(t, targetDir); !maps.Equal(got, before) {
+		t.Errorf("dry run changed target defs:\n%v\nwant\n%v", got, before)
+	}
+}
+
+func TestApplyDirErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		change    Change
+		noSource  bool
+		result    string
+		errSubstr string
+	}{
+		{
+			name:      "added feature exists with other definition",
+			change:    Change{Feature: "eo_pole", Section: so.SectionFeature, State: Added},
+			result:    "eo_pole: no changes",
+			errSubstr: "added feature already exists",
+		},
+		{
+			name:      "added feature without source dir",
+			change:    Change{Feature: "eo_duct", Section: so.SectionFeature, State: Added},
+			noSource:  true,
+			errSubstr: "source dir is required",
+		},
+		{
+			name:      "added field without source dir",
+			change:    Change{Feature: "eo_cable", Section: so.SectionFields, Name: "label", State: Added},
+			noSource:  true,
+			result:    "eo_cable: skipped 1",
+			errSubstr: "no source definition",
+		},
+		{
+			name:      "removed field with blocking reference",
+			change:    Change{Feature: "eo_manhole", Section: so.SectionFields, Name: "status", State: Removed},
+			result:    "eo_manhole: skipped 1",
+			errSubstr: so.ErrBlockingReference.Error(),
+		},
+		{
+			name:      "missing target def",
+			change:    Change{Feature: "eo_duct", Section: so.SectionFields, Name: "id", State: Removed},
+			errSubstr: "eo_duct.def",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceDir, targetDir := applyDirs(t, map[string]string{
+				"eo_cable": targetCable,
+				"eo_pole":  `{"name": "eo_pole", "fields": [{"name": "id", "type": "string"}]}`,
+				"eo_manhole": `{"name": "eo_manhole", "fields": [{"name": "status", "type": "string(10)"}],
+					"queries": [{"value": "Planned", "filter": "[status] = 'planned'"}]}`,
+			})
+			if tt.noSource {
+				sourceDir = ""
+			}
+			before := readDefs(t, targetDir)
+			cs := &Changeset{Version: Version, Changes: []Change{tt.change}}
+			var diag so.Diagnostics
+			results := ApplyDir(cs, ApplyOptions{TargetDir: targetDir, SourceDir: sourceDir}, &diag)
+			var want []string
+			if tt.result != "" {
+				want = []string{tt.result}
+			}
+			if got := resultStrings(results); !slices.Equal(got, want) {
+				t.Errorf("results = %q, want %q", got, want)
+			}
+			if errs := errorStrings(&diag); len(errs) != 1 || !strings.Contains(errs[0], tt.errSubstr) {
+				t.Errorf("errors = %q, want one error with %q", errs, tt.errSubstr)
+			}
+			if got := readDefs(t, targetDir); !maps.Equal(got, before) {
+				t.Errorf("target defs changed:\n%v\nwant\n%v", got, before)
+			}
+		})
+	}
+}
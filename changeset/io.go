@@ -0,0 +1,266 @@
+package changeset
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	so "github.com/kpawlik/superobject"
+)
+
+// Format of the changeset file
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+)
+
+// Set implements flag.Value
+func (f *Format) Set(value string) error {
+	switch format := Format(value); format {
+	case FormatJSON, FormatCSV:
+		*f = format
+		return nil
+	}
+	return fmt.Errorf("unknown changeset format %q, expected %s or %s", value, FormatJSON, FormatCSV)
+}
+
+func (f *Format) String() string {
+	if f == nil || *f == "" {
+		return string(FormatCSV)
+	}
+	return string(*f)
+}
+
+// FormatOf returns format of the changeset file from its extension, .json is JSON, other files are CSV
+func FormatOf(path string) Format {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return FormatJSON
+	}
+	return FormatCSV
+}
+
+const csvVersionPrefix = "# changeset version "
+
+// CSVHeader are columns of the changeset CSV. Each changed attribute is a row,
+// change columns are repeated in rows of its attributes.
+var CSVHeader = []string{"Feature", "Section", "Name", "State", "Type change", "Attribute", "Attribute state", "Old", "New"}
+
+// Write writes the changeset in the format
+func Write(w io.Writer, cs *Changeset, format Format) error {
+	if format == FormatJSON {
+		return WriteJSON(w, cs)
+	}
+	return WriteCSV(w, cs)
+}
+
+// WriteJSON writes the changeset as indented JSON
+func WriteJSON(w io.Writer, cs *Changeset) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", so.FormatIndent)
+	if err := encoder.Encode(cs); err != nil {
+		return fmt.Errorf("failed to write changeset: %w", err)
+	}
+	return nil
+}
+
+// WriteCSV writes the changeset as CSV, the first line is the version comment, the second one CSVHeader
+func WriteCSV(w io.Writer, cs *Changeset) error {
+	if _, err := fmt.Fprintf(w, "%s%d\n", csvVersionPrefix, Version); err != nil {
+		return fmt.Errorf("failed to write changeset: %w", err)
+	}
+	writer := csv.NewWriter(w)
+	writer.Write(CSVHeader)
+	for _, change := range cs.Changes {
+		row := []string{change.Feature, change.Section, change.Name, string(change.State), change.TypeChange}
+		if len(change.Attributes) == 0 {
+			writer.Write(append(row, "", "", "", ""))
+			continue
+		}
+		for _, attr := range change.Attributes {
+			writer.Write(append(slices.Clone(row), attr.Path, string(attr.State), string(attr.Old), string(attr.New)))
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write changeset: %w", err)
+	}
+	return nil
+}
+
+// WriteFile writes the changeset to the file with superobject.WriteFileAtomic, in format of the file extension
+func WriteFile(path string, cs *Changeset, opts so.WriteOptions) error {
+	var buf bytes.Buffer
+	if err := Write(&buf, cs, FormatOf(path)); err != nil {
+		return err
+	}
+	return so.WriteFileAtomic(path, buf.Bytes(), opts)
+}
+
+// ReadFile reads the changeset file, see Read
+func ReadFile(path string) (cs *Changeset, err error) {
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		return nil, fmt.Errorf("failed to read changeset: %w", err)
+	}
+	if cs, err = Read(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return
+}
+
+// Read reads the changeset. JSON or CSV format is detected from the content.
+// CSV written by compare-feature-defs before the changeset format, with header "Feature,Field,...",
+// is read too, with added and removed fields and features only.
+func Read(r io.Reader) (cs *Changeset, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read changeset: %w", err)
+	}
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return readJSON(data)
+	case bytes.HasPrefix(trimmed, []byte(csvVersionPrefix)):
+		return readCSV(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("Feature,Field,")):
+		return readLegacyCSV(trimmed)
+	}
+	return nil, errors.New("unknown changeset format, expected JSON or CSV with header")
+}
+
+func readJSON(data []byte) (cs *Changeset, err error) {
+	cs = &Changeset{}
+	if err = json.Unmarshal(data, cs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal changeset: %w", err)
+	}
+	if err = checkVersion(cs.Version); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func checkVersion(version int) error {
+	if version < 1 || version > Version {
+		return fmt.Errorf("unsupported changeset version %d, expected 1 to %d", version, Version)
+	}
+	return nil
+}
+
+func readCSV(data []byte) (cs *Changeset, err error) {
+	versionLine, rest, _ := bytes.Cut(data, []byte("\n"))
+	version, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(string(versionLine), csvVersionPrefix)))
+	if err != nil {
+		return nil, fmt.Errorf("malformed changeset version line %q", versionLine)
+	}
+	if err = checkVersion(version); err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(bytes.NewReader(rest))
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read changeset header: %w", err)
+	}
+	columns := map[string]int{}
+	for _, name := range CSVHeader {
+		if columns[name] = slices.Index(header, name); columns[name] < 0 {
+			return nil, fmt.Errorf("changeset header: missing column %q", name)
+		}
+	}
+	cs = New("", "")
+	cs.Version = version
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read changeset: %w", err)
+		}
+		change := Change{
+			Feature:    row[columns["Feature"]],
+			Section:    row[columns["Section"]],
+			Name:       row[columns["Name"]],
+			State:      so.DiffState(row[columns["State"]]),
+			TypeChange: row[columns["Type change"]],
+		}
+		// rows of attributes of the same change follow each other
+		last := len(cs.Changes) - 1
+		if last < 0 || !sameChange(cs.Changes[last], change) {
+			cs.Changes = append(cs.Changes, change)
+			last++
+		}
+		if path := row[columns["Attribute"]]; path != "" || row[columns["Attribute state"]] != "" {
+			cs.Changes[last].Attributes = append(cs.Changes[last].Attributes, Attribute{
+				Path:  path,
+				State: so.DiffState(row[columns["Attribute state"]]),
+				Old:   rawColumn(row[columns["Old"]]),
+				New:   rawColumn(row[columns["New"]]),
+			})
+		}
+	}
+	return
+}
+
+func sameChange(c1, c2 Change) bool {
+	return c1.Feature == c2.Feature && c1.Section == c2.Section && c1.Name == c2.Name && c1.State == c2.State
+}
+
+func rawColumn(value string) json.RawMessage {
+	if value == "" {
+		return nil
+	}
+	return json.RawMessage(value)
+}
+
+// readLegacyCSV reads CSV of compare-feature-defs: feature, field, state in dir 1, state in dir 2, ..., optional Section column
+func readLegacyCSV(data []byte) (cs *Changeset, err error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read changeset header: %w", err)
+	}
+	if len(header) < 4 {
+		return nil, fmt.Errorf("changeset header: expected at least 4 columns, got %d", len(header))
+	}
+	sectionColumn := slices.Index(header, "Section")
+	cs = New(header[2], header[3])
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read changeset: %w", err)
+		}
+		if len(row) < 4 || row[0] == "" {
+			continue
+		}
+		change := Change{Feature: row[0], Section: so.SectionFields, Name: row[1]}
+		if sectionColumn >= 0 && sectionColumn < len(row) {
+			change.Section = row[sectionColumn]
+		}
+		switch {
+		case row[2] == string(Removed):
+			change.State = Removed
+		case row[3] == string(Added):
+			change.State = Added
+		default:
+			// changed rows have no attribute values in this format
+			continue
+		}
+		cs.Changes = append(cs.Changes, change)
+	}
+	return
+}
@@ -0,0 +1,126 @@
+package changeset
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	so "github.com/kpawlik/superobject"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes []Change
+	}{
+		{name: "empty", changes: []Change{}},
+		{
+			name: "added and removed",
+			changes: []Change{
+				{Feature: "eo_cable", Section: so.SectionFields, Name: "created_by", State: Removed},
+				{Feature: "eo_cable", Section: so.SectionFields, Name: "new_f", State: Added},
+				{Feature: "eo_pole", Section: so.SectionFeature, State: Added},
+			},
+		},
+		{
+			name: "changed attributes",
+			changes: []Change{
+				{Feature: "eo_cable", Section: so.SectionFields, Name: "name", State: Changed, TypeChange: "lossy", Attributes: []Attribute{
+					{Path: "type", State: Changed, Old: json.RawMessage(`"string(100)"`), New: json.RawMessage(`"string(50)"`)},
+					{Path: "unit", State: Added, New: json.RawMessage(`"kV"`)},
+					{Path: "mandatory", State: Removed, Old: json.RawMessage(`true`)},
+				}},
+				{Feature: "eo_cable", Section: "title", State: Changed, Attributes: []Attribute{
+					{State: Changed, Old: json.RawMessage(`"{name}, \"{id}\"\n"`), New: json.RawMessage(`"{name}"`)},
+				}},
+				{Feature: "eo_cable", Section: "groups", Name: "Algemeen", State: Changed, Attributes: []Attribute{
+					{Path: "fields[1]", State: Changed, Old: json.RawMessage(`"a"`), New: json.RawMessage(`"b"`)},
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := New("Dir 1", "Dir 2")
+			cs.Changes = tt.changes
+			var jsonData bytes.Buffer
+			if err := Write(&jsonData, cs, FormatJSON); err != nil {
+				t.Fatal(err)
+			}
+			fromJSON, err := Read(&jsonData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(fromJSON, cs) {
+				t.Errorf("JSON round trip = %+v, want %+v", fromJSON, cs)
+			}
+			var csvData bytes.Buffer
+			if err = Write(&csvData, fromJSON, FormatCSV); err != nil {
+				t.Fatal(err)
+			}
+			if want := csvVersionPrefix + "1\n" + strings.Join(CSVHeader, ",") + "\n"; !strings.HasPrefix(csvData.String(), want) {
+				t.Errorf("CSV starts with %q, want %q", csvData.String(), want)
+			}
+			fromCSV, err := Read(&csvData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// CSV has no names of compared versions
+			if fromCSV.Version != Version || !reflect.DeepEqual(fromCSV.Changes, cs.Changes) {
+				t.Errorf("CSV round trip = %+v, want %+v", fromCSV, cs)
+			}
+		})
+	}
+}
+
+func TestReadVersion(t *testing.T) {
+	header := strings.Join(CSVHeader, ",") + "\n"
+	tests := []struct {
+		name    string
+		data    string
+		version int
+		err     string
+	}{
+		{name: "JSON", data: `{"version": 1, "changes": []}`, version: 1},
+		{name: "JSON newer version", data: `{"version": 2, "changes": []}`, err: "unsupported changeset version 2"},
+		{name: "JSON without version", data: `{"changes": []}`, err: "unsupported changeset version 0"},
+		{name: "CSV", data: "# changeset version 1\n" + header, version: 1},
+		{name: "CSV newer version", data: "# changeset version 2\n" + header, err: "unsupported changeset version 2"},
+		{name: "CSV malformed version", data: "# changeset version x\n" + header, err: "malformed changeset version line"},
+		{name: "CSV missing column", data: "# changeset version 1\nFeature,Section\n", err: `missing column "Name"`},
+		{name: "legacy CSV", data: "Feature,Field,Dir 1,Dir 2\neo_cable,voltage,removed,\n", version: 1},
+		{name: "unknown format", data: "feature;field\n", err: "unknown changeset format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs, err := Read(strings.NewReader(tt.data))
+			switch {
+			case tt.err != "":
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error = %v, want %q", err, tt.err)
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case cs.Version != tt.version:
+				t.Errorf("version = %d, want %d", cs.Version, tt.version)
+			}
+		})
+	}
+}
+
+func TestReadLegacyCSV(t *testing.T) {
+	data := "Feature,Field,Dir 1,Dir 2,Section\neo_cable,voltage,removed,,fields\neo_cable,unit,,added,fields\neo_cable,name,changed,changed,fields\n"
+	cs, err := Read(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{
+		{Feature: "eo_cable", Section: so.SectionFields, Name: "voltage", State: Removed},
+		{Feature: "eo_cable", Section: so.SectionFields, Name: "unit", State: Added},
+	}
+	if cs.Name1 != "Dir 1" || cs.Name2 != "Dir 2" || !reflect.DeepEqual(cs.Changes, want) {
+		t.Errorf("changeset = %+v, want changes %+v", cs, want)
+	}
+}
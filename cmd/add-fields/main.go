@@ -1,95 +1,81 @@
 package main
 
 import (
-	"encoding/csv"
 	"flag"
-	"io"
+	"fmt"
 	"os"
 	"path/filepath"
 	"slices"
-	"strings"
 
-	"github.com/kpawlik/om"
 	so "github.com/kpawlik/superobject"
+	"github.com/kpawlik/superobject/changeset"
 )
 
-var(
-	FeatureDir string
-	SourceDir string
+var (
+	FeatureDir     string
+	SourceDir      string
 	FieldsDiffFile string
+	Backup         = so.BackupNone
+	Verbose        bool
 )
 
-
 func init() {
 	flag.StringVar(&FeatureDir, "target-dir", "", "Feature dir")
 	flag.StringVar(&SourceDir, "source-dir", "", "Source dir")
-	flag.StringVar(&FieldsDiffFile, "cmp-file", "", "Fields diff file")	
+	flag.StringVar(&FieldsDiffFile, "cmp-file", "", "Changeset file of compare-feature-defs, JSON or CSV")
+	flag.Var(&Backup, "backup", "Backup of modified def files: none, bak or timestamp")
+	flag.BoolVar(&Verbose, "v", false, "Print added fields with their groups")
 	flag.Parse()
 }
 
 func main() {
-	csvContent, err := os.ReadFile(FieldsDiffFile)
-	so.HandleErr(err)
-	reader := strings.NewReader(string(csvContent))
-	csvReader := csv.NewReader(reader)
-	fieldsToAdd := make(map[string][]string)
-	for{
-		row, err  := csvReader.Read()
-		if err == io.EOF {
-			break
+	diag := &so.Diagnostics{}
+	cs, err := changeset.ReadFile(FieldsDiffFile)
+	if diag.Error(err) {
+		os.Exit(diag.Report(os.Stderr))
+	}
+	fieldsToAdd := cs.Fields(changeset.Added)
+	for _, feature := range cs.Features() {
+		fields, ok := fieldsToAdd[feature]
+		if !ok {
+			continue
 		}
-		feature := row[0]
-		if feature == "" {
+		featurePath := filepath.Join(FeatureDir, feature+".def")
+		sourcePath := filepath.Join(SourceDir, feature+".def")
+		addFields(featurePath, sourcePath, fields, diag)
+	}
+	os.Exit(diag.Report(os.Stderr))
+}
+
+// addFields copies fields from the source def to the feature def. Fields are added in the source order,
+// so each one is inserted after its preceding neighbour, and to the groups which hold them in the source def.
+func addFields(featurePath string, sourcePath string, fieldsToAdd []string, diag *so.Diagnostics) {
+	featureDef, err := so.ReadDefFile(featurePath)
+	if diag.Error(err) {
+		return
+	}
+	sourceDef, err := so.ReadDefFile(sourcePath)
+	if diag.Error(err) {
+		return
+	}
+	for _, fieldName := range fieldsToAdd {
+		if !sourceDef.HasField(fieldName) {
+			diag.Error(&so.FieldNotFoundError{Path: sourcePath, Feature: sourceDef.Name(), Field: fieldName})
+		}
+	}
+	for _, fieldName := range sourceDef.FieldNames() {
+		if !slices.Contains(fieldsToAdd, fieldName) {
 			continue
 		}
-		field := row[1]
-		removed := row[3]
-		if removed != "added" {
+		if featureDef.HasField(fieldName) {
+			diag.Warningf("%s: field %s already exists, not added", featurePath, fieldName)
 			continue
 		}
-		if _, ok := fieldsToAdd[feature]; !ok {
-			fieldsToAdd[feature] = make([]string, 0)
+		diag.Error(so.CopyField(featureDef, sourceDef, fieldName))
+		groups, created := so.CopyFieldGroups(featureDef, sourceDef, fieldName)
+		if Verbose {
+			fmt.Printf("%s: added %s, groups: %v, created groups: %v\n", featureDef.Name(), fieldName, groups, created)
 		}
-		fieldsToAdd[feature] = append(fieldsToAdd[feature], field)
-	} 
-	for feature, fields := range fieldsToAdd {
-		featurePath := filepath.Join(FeatureDir, feature + ".def")
-		sourcePath := filepath.Join(SourceDir, feature + ".def")
-		addFields(featurePath, sourcePath, fields)
-
 	}
-	
+	diag.Error(so.WriteDefFile(featurePath, featureDef, so.WriteOptions{Backup: Backup}))
 }
-
-func addFields(featurePath string, sourcePath string, fieldsToAdd []string) {
-	var (
-		fileContent []byte
-		err         error
-	)
-	fileContent, err = os.ReadFile(featurePath)
-	so.HandleErr(err)
-	featureOm := om.NewOrderedMap()
-	err = featureOm.UnmarshalJSON(fileContent)
-	so.HandleErr(err)
-	fileContent, err = os.ReadFile(sourcePath)
-	so.HandleErr(err)
-	sourceOm := om.NewOrderedMap()
-	err = sourceOm.UnmarshalJSON(fileContent)
-	so.HandleErr(err)
-	featureFields:= featureOm.Map["fields"].([]any)
-	sourceFields := sourceOm.Map["fields"].([]any)
-	for _, sourceField := range sourceFields {
-		fieldName := sourceField.(*om.OrderedMap).Map["name"].(string)
-		if slices.Contains(fieldsToAdd, fieldName) {
-			featureFields = append(featureFields, sourceField)
-		}
-		
-	}
-	featureOm.Map["fields"] = featureFields
-	fileContent, err = featureOm.MarshalIndent("    ")
-	so.HandleErr(err)
-	err = os.WriteFile(featurePath, fileContent, 0644)
-	so.HandleErr(err)
-
-	
-}
\ No newline at end of file
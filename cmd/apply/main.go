@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	so "github.com/kpawlik/superobject"
+	"github.com/kpawlik/superobject/changeset"
+)
+
+var (
+	changesetPath string
+	targetDir     string
+	sourceDir     string
+	dryRun        bool
+	verbose       bool
+	backup        = so.BackupNone
+)
+
+func init() {
+	flag.StringVar(&changesetPath, "changeset", "", "Changeset file of compare-feature-defs, JSON or CSV")
+	flag.StringVar(&targetDir, "target-dir", "", "Dir of defs the changes are applied to")
+	flag.StringVar(&sourceDir, "source-dir", "", "Dir of defs added fields and features are copied from, usually dir2 of compare-feature-defs")
+	flag.BoolVar(&dryRun, "dry-run", false, "Print summary of changes without writing files")
+	flag.BoolVar(&verbose, "v", false, "Print names of changed fields and skipped changes")
+	flag.Var(&backup, "backup", "Backup of modified and removed def files: none, bak or timestamp")
+	flag.Parse()
+}
+
+func main() {
+	diag := &so.Diagnostics{}
+	if changesetPath == "" || targetDir == "" {
+		diag.Errorf("-changeset and -target-dir are required")
+		os.Exit(diag.Report(os.Stderr))
+	}
+	cs, err := changeset.ReadFile(changesetPath)
+	if diag.Error(err) {
+		os.Exit(diag.Report(os.Stderr))
+	}
+	opts := changeset.ApplyOptions{TargetDir: targetDir, SourceDir: sourceDir, DryRun: dryRun, Backup: backup}
+	for _, result := range changeset.ApplyDir(cs, opts, diag) {
+		printResult(result)
+	}
+	if dryRun {
+		fmt.Println("dry run, no files were written")
+	}
+	os.Exit(diag.Report(os.Stderr))
+}
+
+func printResult(result *changeset.Result) {
+	fmt.Println(result)
+	if !verbose {
+		return
+	}
+	for _, items := range []struct {
+		name   string
+		values []string
+	}{{"added", result.Added}, {"removed", result.Removed}, {"modified", result.Modified}, {"unchanged", result.Unchanged}, {"skipped", result.Skipped}} {
+		if len(items.values) > 0 {
+			fmt.Printf("  %s: %s\n", items.name, strings.Join(items.values, ", "))
+		}
+	}
+}
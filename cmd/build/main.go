@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	so "github.com/kpawlik/superobject"
+)
+
+var (
+	manifestPath string
+	defsDir      string
+	outDir       string
+	only         string
+	list         bool
+	backup       = so.BackupNone
+	keepStale    bool
+	verbose      bool
+)
+
+func init() {
+	flag.StringVar(&manifestPath, "manifest", "", "Path to super objects manifest file")
+	flag.StringVar(&defsDir, "defs", "", "Dir with dumped super object and component def files")
+	flag.StringVar(&outDir, "out", "", "Dir where composed def files are written")
+	flag.StringVar(&only, "only", "", "Comma separated list of super objects to build. Default is all super objects from manifest")
+	flag.Var(&backup, "backup", "Backup of overwritten files: none, bak or timestamp")
+	flag.BoolVar(&keepStale, "keep-stale", false, "Keep calculated fields whose source field no longer exists in the component or is excluded. Default is to remove them")
+	flag.BoolVar(&verbose, "v", false, "Print excluded component fields with the rule which excluded them")
+	flag.BoolVar(&list, "list", false, "Print names of all features used in manifest, one per line, and exit")
+	flag.Parse()
+	if manifestPath == "" || (!list && (defsDir == "" || outDir == "")) {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+}
+
+func main() {
+	manifest, err := so.ReadManifest(manifestPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if list {
+		for _, name := range manifest.Features() {
+			fmt.Println(name)
+		}
+		return
+	}
+	specs := manifest.SuperObjects
+	if only != "" {
+		specs = nil
+		for _, name := range strings.Split(only, ",") {
+			spec := manifest.SuperObject(strings.TrimSpace(name))
+			if spec == nil {
+				log.Fatalf("super object %s not found in manifest %s", name, manifestPath)
+			}
+			specs = append(specs, *spec)
+		}
+	}
+	diag := &so.Diagnostics{}
+	composer := so.NewComposer()
+	composer.KeepStale = keepStale
+	for _, spec := range specs {
+		diag.Error(build(spec, composer, diag))
+	}
+	os.Exit(diag.Report(os.Stderr))
+}
+
+// build composes one super object and writes its def and methods files
+func build(spec so.SuperObjectSpec, composer *so.Composer, diag *so.Diagnostics) (err error) {
+	var (
+		def    *so.FeatureDef
+		report *so.ComposeReport
+	)
+	if def, report, err = so.BuildSuperObject(spec, defsDir, composer); err != nil {
+		return fmt.Errorf("super object %s: %w", spec.Name, err)
+	}
+	methods, err := composer.RenderMethods(def)
+	if err != nil {
+		return fmt.Errorf("super object %s: %w", spec.Name, err)
+	}
+	for _, component := range report.Components {
+		for _, field := range component.Fields {
+			switch field.Action {
+			case so.FieldRemoved:
+				log.Printf("%s: %s: removed stale field %s", spec.Name, component.Component, field.Name)
+			case so.FieldStale:
+				log.Printf("%s: %s: kept stale field %s", spec.Name, component.Component, field.Name)
+			}
+		}
+		if verbose {
+			for _, excluded := range component.Excluded {
+				log.Printf("%s: %s.%s excluded by rule: %s", spec.Name, component.Component, excluded.Name, excluded.Rule)
+			}
+		}
+		log.Printf("%s: %s: %d added, %d updated, %d unchanged, %d removed fields in group %s",
+			spec.Name, component.Component, component.Count(so.FieldAdded), component.Count(so.FieldUpdated),
+			component.Count(so.FieldUnchanged), component.Count(so.FieldRemoved), component.Group)
+	}
+	writeOptions := so.WriteOptions{Backup: backup}
+	destPath := filepath.Join(outDir, spec.Name+".def")
+	if err = so.WriteDefFile(destPath, def, writeOptions); err != nil {
+		return
+	}
+	if err = so.WriteFileAtomic(destPath+"_methods.txt", methods, writeOptions); err != nil {
+		return
+	}
+	// super object is built from scratch, so the lock is too
+	lock := &so.Lock{Path: so.LockPath(destPath)}
+	lock.Update(report)
+	if err = lock.Write(writeOptions); err != nil {
+		return
+	}
+	// enumerators of calculated fields must be available next to the super object
+	enums := composer.CopyEnums(def, []string{defsDir}, outDir, writeOptions, diag)
+	if len(enums.Copied) > 0 {
+		log.Printf("%s: enumerators copied: %s", spec.Name, strings.Join(enums.Copied, ", "))
+	}
+	return
+}
@@ -1,67 +1,108 @@
 package main
 
 import (
-	"encoding/csv"
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	so "github.com/kpawlik/superobject"
+	"github.com/kpawlik/superobject/changeset"
+	"github.com/kpawlik/superobject/usage"
 )
 
 var (
 	csvPath      string
 	databaseName string
-	sql = 		`select feature_name, display_name, field_name from myw.dd_field_group fg 
-		join myw.dd_field_group_item fgi on fg.id=fgi.container_id 
-		where feature_name = '%s'  
-		and field_name in (%s);`
+	dumpPath     string
+	jsonOutput   bool
+	outputPath   string
+	schema       string
 )
 
 func init() {
-	flag.StringVar(&csvPath, "csv", "", "Path to the CSV file")
-	flag.StringVar(&databaseName, "db", "iqgeo-test", "Name of the database")	
+	flag.StringVar(&csvPath, "csv", "", "Path to the changeset file of compare-feature-defs, JSON or CSV")
+	flag.StringVar(&databaseName, "db", "iqgeo-test", "Name of the database, used in the psql command in the SQL file")
+	flag.StringVar(&dumpPath, "dump", "", "Path to PostgreSQL plain-text dump. Usages of removed fields are reported from the dump instead of writing SQL")
+	flag.BoolVar(&jsonOutput, "json", false, "Print usage report of -dump as JSON")
+	flag.StringVar(&outputPath, "o", "", "Path to the SQL file. Default is stdout")
+	flag.StringVar(&schema, "schema", usage.DefaultSchema, "Schema of myWorld configuration tables")
 	flag.Parse()
 	if csvPath == "" {
-		panic("CSV path is required")
-	}	
-
+		fmt.Fprintln(os.Stderr, "-csv is required")
+		flag.Usage()
+		os.Exit(2)
+	}
 }
 
 func main() {
-	csvContent, err := os.ReadFile(csvPath)
-	so.HandleErr(err)
-	reader := strings.NewReader(string(csvContent))
-	csvReader := csv.NewReader(reader)
-	fieldsToRemove := make(map[string][]string)
-	for{
-		row, err  := csvReader.Read()
-		if err == io.EOF {
-			break
-		}
-		feature := row[0]
-		if feature == "" {
-			continue
-		}
-		field := row[1]
-		removed := row[2]
-		if removed != "removed" {
+	diag := &so.Diagnostics{}
+	cs, err := changeset.ReadFile(csvPath)
+	if diag.Error(err) {
+		os.Exit(diag.Report(os.Stderr))
+	}
+	removed := cs.Fields(changeset.Removed)
+	if dumpPath == "" {
+		diag.Error(writeSQL(removed))
+		os.Exit(diag.Report(os.Stderr))
+	}
+	dump, err := usage.ReadDumpFile(dumpPath, usage.Tables...)
+	if diag.Error(err) {
+		os.Exit(diag.Report(os.Stderr))
+	}
+	report := usage.Analyze(dump, removed)
+	for _, table := range report.MissingTables {
+		diag.Warningf("%s: no data of table %s, usages in it are not reported", dumpPath, table)
+	}
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", so.FormatIndent)
+		diag.Error(encoder.Encode(report))
+	} else {
+		printReport(report)
+	}
+	if code := diag.Report(os.Stderr); code != 0 {
+		os.Exit(code)
+	}
+	if len(report.Used()) > 0 {
+		os.Exit(1)
+	}
+}
+
+func printReport(report *usage.Report) {
+	for _, field := range report.Fields {
+		if !field.Used() {
+			fmt.Printf("%s.%s: not used\n", field.Feature, field.Field)
 			continue
 		}
-		if _, ok := fieldsToRemove[feature]; !ok {
-			fieldsToRemove[feature] = make([]string, 0)
+		fmt.Printf("%s.%s: %d usages\n", field.Feature, field.Field, len(field.Usages))
+		for _, u := range field.Usages {
+			fmt.Printf("  %s\n", u)
 		}
-		fieldsToRemove[feature] = append(fieldsToRemove[feature], fmt.Sprintf("'%s'", field))
-	} 
-	for feature, fields := range fieldsToRemove {
-		sqlStr := fmt.Sprintf(sql, feature, strings.Join(fields, ", "))
-		fmt.Printf("echo %s\n", feature)
-		fmt.Printf("echo \"%s\"\n", sqlStr)
-		fmt.Printf("psql -d %s -c \"%s\"\n", databaseName, sqlStr)
-		fmt.Printf("echo \"=======\"\n")
-		fmt.Println()	
+	}
+}
 
+// writeSQL writes the SQL file with one query of removed fields used in field groups
+func writeSQL(removed map[string][]string) error {
+	var buf bytes.Buffer
+	output := outputPath
+	if output == "" {
+		output = "<file>"
+	}
+	fmt.Fprintf(&buf, "-- Removed fields of %s used in field groups\n", sqlComment(filepath.Base(csvPath)))
+	fmt.Fprintf(&buf, "-- psql -d %s -f %s\n", sqlComment(databaseName), sqlComment(output))
+	buf.WriteString(usage.UsedFieldsSQL(schema, removed))
+	if outputPath == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
 	}
-}
\ No newline at end of file
+	return so.WriteFileAtomic(outputPath, buf.Bytes(), so.WriteOptions{})
+}
+
+// sqlComment returns the text without line breaks, so it can not end the comment line
+func sqlComment(text string) string {
+	return strings.NewReplacer("\n", " ", "\r", " ").Replace(text)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+
+	so "github.com/kpawlik/superobject"
+)
+
+var (
+	soSource  string
+	soDest    string
+	component string
+	dryRun    bool
+	backup    = so.BackupNone
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s -source file.def -component name [methods.txt | file.js]...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.StringVar(&soSource, "source", "", "Path to composed superobject def file")
+	flag.StringVar(&soDest, "dest", "", "Path to destination def file. Default is source")
+	flag.StringVar(&component, "component", "", "Comma separated list of components to remove from the superobject")
+	flag.BoolVar(&dryRun, "n", false, "Print what would be removed, do not write files")
+	flag.Var(&backup, "backup", "Backup of overwritten files: none, bak or timestamp")
+	flag.Parse()
+	if soSource == "" || component == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if soDest == "" {
+		soDest = soSource
+	}
+}
+
+func main() {
+	def, err := so.ReadDefFile(soSource)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var (
+		diag     so.Diagnostics
+		methods  []string
+		composer = so.NewComposer()
+	)
+	for _, name := range strings.Split(component, ",") {
+		result, report, err := composer.Decompose(def, strings.TrimSpace(name))
+		if diag.Error(err) {
+			continue
+		}
+		def = result
+		methods = append(methods, report.Methods...)
+		log.Printf("%s: removed %d fields of %s, groups: %s", report.Feature, len(report.Fields), report.Component, strings.Join(report.Groups, ", "))
+		for _, group := range report.KeptGroups {
+			diag.Warningf("%s: group %s had fields of %s, kept with other fields: %s",
+				report.Feature, group, report.Component, strings.Join(def.Group(group).Fields(), ", "))
+		}
+	}
+	if diag.HasErrors() {
+		os.Exit(diag.Report(os.Stderr))
+	}
+	writeOptions := so.WriteOptions{Backup: backup}
+	if !dryRun {
+		if err = so.WriteDefFile(soDest, def, writeOptions); err != nil {
+			log.Fatal(err)
+		}
+	}
+	lock, err := so.ReadLockOrNew(so.LockPath(soSource), def.Name())
+	if diag.Error(err) {
+		os.Exit(diag.Report(os.Stderr))
+	}
+	lock.SetPath(so.LockPath(soDest))
+	removed := false
+	for _, name := range strings.Split(component, ",") {
+		removed = lock.Remove(strings.TrimSpace(name)) || removed
+	}
+	if !dryRun && (removed || len(lock.Components) > 0) {
+		diag.Error(lock.Write(writeOptions))
+	}
+	// strip methods of removed fields from generated methods and JS files
+	for _, path := range flag.Args() {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			diag.Warning(err)
+			continue
+		}
+		if diag.Error(err) {
+			continue
+		}
+		res, removed := so.RemoveMethods(data, methods)
+		log.Printf("%s: removed %d of %d methods", path, len(removed), len(methods))
+		if dryRun || len(removed) == 0 {
+			continue
+		}
+		diag.Error(so.WriteFileAtomic(path, res, so.WriteOptions{Backup: backup, ExpectedHash: so.FileHash(data)}))
+	}
+	os.Exit(diag.Report(os.Stderr))
+}
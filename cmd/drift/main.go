@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	so "github.com/kpawlik/superobject"
+)
+
+var (
+	defsDir    string
+	jsonOutput bool
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-defs dir] [file.def | dir]...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.StringVar(&defsDir, "defs", "", "Dir with current component def files. Default is dir of each super object")
+	flag.BoolVar(&jsonOutput, "json", false, "Print report as JSON")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func main() {
+	var (
+		diag     so.Diagnostics
+		reports  = []*so.DriftReport{}
+		drifted  bool
+		composer = so.NewComposer()
+	)
+	for _, path := range so.ExpandDefPaths(flag.Args(), &diag) {
+		def, err := so.ReadDefFile(path)
+		if diag.Error(err) {
+			continue
+		}
+		report := &so.DriftReport{Feature: def.Name(), Path: path, Components: composer.Components(def), Drifts: []so.Drift{}}
+		if len(report.Components) == 0 {
+			// not a super object
+			continue
+		}
+		for _, name := range report.Components {
+			component, err := readComponent(path, name)
+			if diag.Error(err) {
+				continue
+			}
+			report.Drifts = append(report.Drifts, componentComposer(composer, path, name).Drift(def, name, component)...)
+		}
+		drifted = drifted || len(report.Drifts) > 0
+		reports = append(reports, report)
+	}
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", so.FormatIndent)
+		diag.Error(encoder.Encode(reports))
+	} else {
+		for _, report := range reports {
+			printReport(report)
+		}
+	}
+	if code := diag.Report(os.Stderr); code != 0 {
+		os.Exit(code)
+	}
+	if drifted {
+		os.Exit(1)
+	}
+}
+
+// readComponent reads current definition of the component, nil if it does not exist
+func readComponent(superObjectPath string, name string) (*so.FeatureDef, error) {
+	dir := defsDir
+	if dir == "" {
+		dir = filepath.Dir(superObjectPath)
+	}
+	component, err := so.ReadDefFile(filepath.Join(dir, name+".def"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return component, err
+}
+
+// componentComposer returns composer with rules used when the super object was built, if it has a lock file
+func componentComposer(composer *so.Composer, superObjectPath string, name string) *so.Composer {
+	lock, err := so.ReadLock(so.LockPath(superObjectPath))
+	if err != nil {
+		return composer
+	}
+	for _, component := range lock.Components {
+		if component.Name != name {
+			continue
+		}
+		rules, err := so.ParseRuleSet(component.Rules)
+		if err != nil {
+			return composer
+		}
+		lockComposer := *composer
+		lockComposer.ExcludedTypes = []string{}
+		lockComposer.Rules = rules
+		return &lockComposer
+	}
+	return composer
+}
+
+func printReport(report *so.DriftReport) {
+	if len(report.Drifts) == 0 {
+		fmt.Printf("%s: ok\n", report.Feature)
+		return
+	}
+	fmt.Printf("%s: %d differences\n", report.Feature, len(report.Drifts))
+	for _, drift := range report.Drifts {
+		switch drift.Kind {
+		case so.DriftMissingComponent:
+			fmt.Printf("    %s: component def not found\n", drift.Component)
+		case so.DriftMissingSource:
+			fmt.Printf("    %s: source field %s.%s not found\n", drift.Field, drift.Component, drift.Source)
+		case so.DriftNewField:
+			fmt.Printf("    %s.%s: new field not in super object, type %s\n", drift.Component, drift.Source, drift.New)
+		case so.DriftType:
+			fmt.Printf("    %s: type changed %q -> %q (%s)\n", drift.Field, drift.Old, drift.New, drift.TypeChange)
+		default:
+			fmt.Printf("    %s: %s changed %q -> %q\n", drift.Field, drift.Kind, drift.Old, drift.New)
+		}
+	}
+}
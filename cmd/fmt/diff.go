@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	diffContext = 3
+	// noNewline follows the last line of a text without final newline
+	noNewline = `\ No newline at end of file`
+)
+
+type edit struct {
+	op   byte // ' ', '-' or '+'
+	line string
+}
+
+// unifiedDiff returns unified diff of two texts
+func unifiedDiff(path string, a, b []byte) []byte {
+	edits := diffLines(splitLines(a), splitLines(b))
+	buf := bytes.NewBuffer([]byte{})
+	fmt.Fprintf(buf, "--- %s\n+++ %s (formatted)\n", path, path)
+	// line numbers of the edit in a and b
+	aLines := make([]int, len(edits)+1)
+	bLines := make([]int, len(edits)+1)
+	for i, e := range edits {
+		aLines[i+1], bLines[i+1] = aLines[i], bLines[i]
+		if e.op != '+' {
+			aLines[i+1]++
+		}
+		if e.op != '-' {
+			bLines[i+1]++
+		}
+	}
+	for start := 0; start < len(edits); {
+		if edits[start].op == ' ' {
+			start++
+			continue
+		}
+		// hunk starts with context before the change and ends when
+		// there are more than 2*diffContext equal lines after a change
+		first := max(start-diffContext, 0)
+		end, equal := start, 0
+		for ; end < len(edits) && equal <= 2*diffContext; end++ {
+			if edits[end].op == ' ' {
+				equal++
+			} else {
+				equal = 0
+			}
+		}
+		last := min(end-equal+diffContext, len(edits))
+		fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(aLines[first], aLines[last]), hunkRange(bLines[first], bLines[last]))
+		for _, e := range edits[first:last] {
+			fmt.Fprintf(buf, "%c%s\n", e.op, e.line)
+		}
+		start = last
+	}
+	return buf.Bytes()
+}
+
+func hunkRange(from, to int) string {
+	if to-from == 1 {
+		return fmt.Sprint(from + 1)
+	}
+	if to == from {
+		return fmt.Sprintf("%d,0", from)
+	}
+	return fmt.Sprintf("%d,%d", from+1, to-from)
+}
+
+// splitLines splits text into lines without line endings
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	text := string(data)
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if !strings.HasSuffix(text, "\n") {
+		// like diff(1), the line differs from the same line with newline and is followed by the marker
+		lines[len(lines)-1] += "\n" + noNewline
+	}
+	return lines
+}
+
+// diffLines returns the shortest edit script from a to b. It uses the linear space variant of
+// the Myers algorithm, which splits the texts at the middle snake of an optimal path and
+// diffs both halves, so memory does not grow with the number of differences.
+func diffLines(a, b []string) []edit {
+	d := &differ{a: a, b: b}
+	n, m := len(a), len(b)
+	d.vf = make([]int, 2*(n+m)+3)
+	d.vb = make([]int, 2*(n+m)+3)
+	d.diff(0, n, 0, m)
+	return d.edits
+}
+
+type differ struct {
+	a, b  []string
+	edits []edit
+	// furthest reaching x of forward and backward paths by diagonal, reused by all steps
+	vf, vb []int
+}
+
+// diff appends edits from a[aLo:aHi] to b[bLo:bHi]
+func (d *differ) diff(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.edits = append(d.edits, edit{' ', d.a[aLo]})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aHi-suffix > aLo && bHi-suffix > bLo && d.a[aHi-suffix-1] == d.b[bHi-suffix-1] {
+		suffix++
+	}
+	aHi, bHi = aHi-suffix, bHi-suffix
+	switch x, y, ok := d.middleSnake(aLo, aHi, bLo, bHi); {
+	case aLo == aHi || bLo == bHi || !ok:
+		for _, line := range d.a[aLo:aHi] {
+			d.edits = append(d.edits, edit{'-', line})
+		}
+		for _, line := range d.b[bLo:bHi] {
+			d.edits = append(d.edits, edit{'+', line})
+		}
+	default:
+		d.diff(aLo, x, bLo, y)
+		d.diff(x, aHi, y, bHi)
+	}
+	for _, line := range d.a[aHi : aHi+suffix] {
+		d.edits = append(d.edits, edit{' ', line})
+	}
+}
+
+// middleSnake returns a point of an optimal path from a[aLo:aHi] to b[bLo:bHi] which splits it
+// into two smaller problems, false if there is no such point, e.g. when one of texts is empty
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (int, int, bool) {
+	n, m := aHi-aLo, bHi-bLo
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+	delta := n - m
+	odd := delta%2 != 0
+	// diagonal k is stored at index k+offset
+	offset := n + m + 1
+	vf, vb := d.vf, d.vb
+	vf[offset+1], vb[offset+1] = 0, 0
+	for D := 0; D <= (n+m+1)/2; D++ {
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && vf[offset+k-1] < vf[offset+k+1]) {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			vf[offset+k] = x
+			// backward diagonal of forward diagonal k is delta-k
+			if odd && delta-k >= -(D-1) && delta-k <= D-1 && x+vb[offset+delta-k] >= n {
+				return d.split(aLo, bLo, x, x-k, n, m)
+			}
+		}
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || (k != D && vb[offset+k-1] < vb[offset+k+1]) {
+				x = vb[offset+k+1]
+			} else {
+				x = vb[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aHi-x-1] == d.b[bHi-y-1] {
+				x++
+				y++
+			}
+			vb[offset+k] = x
+			if !odd && delta-k >= -D && delta-k <= D && x+vf[offset+delta-k] >= n {
+				return d.split(aLo, bLo, n-x, m-y, n, m)
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// split returns the point x, y relative to aLo, bLo as absolute, false if it does not split the problem
+func (d *differ) split(aLo, bLo, x, y, n, m int) (int, int, bool) {
+	if (x == 0 && y == 0) || (x == n && y == m) {
+		return 0, 0, false
+	}
+	return aLo + x, bLo + y, true
+}
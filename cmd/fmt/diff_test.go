@@ -0,0 +1,124 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// apply returns both texts of the edit script
+func apply(edits []edit) (a, b []string) {
+	for _, e := range edits {
+		if e.op != '+' {
+			a = append(a, e.line)
+		}
+		if e.op != '-' {
+			b = append(b, e.line)
+		}
+	}
+	return
+}
+
+// lcs returns length of the longest common subsequence
+func lcs(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"equal", "a b c", "a b c"},
+		{"empty a", "", "a b"},
+		{"empty b", "a b", ""},
+		{"replace", "a", "b"},
+		{"insert", "a c", "a b c"},
+		{"delete", "a b c", "a c"},
+		{"reindent", "x a b c y", "x A B C y"},
+		{"moved", "a b c d e", "c d e a b"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := strings.Fields(test.a), strings.Fields(test.b)
+			checkDiff(t, a, b)
+		})
+	}
+}
+
+func TestDiffLinesRandom(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	text := func() (lines []string) {
+		for range random.Intn(30) {
+			lines = append(lines, string(rune('a'+random.Intn(4))))
+		}
+		return
+	}
+	for range 500 {
+		checkDiff(t, text(), text())
+	}
+}
+
+func checkDiff(t *testing.T, a, b []string) {
+	t.Helper()
+	edits := diffLines(a, b)
+	gotA, gotB := apply(edits)
+	if !slices.Equal(gotA, a) || !slices.Equal(gotB, b) {
+		t.Fatalf("diff of %q and %q does not reproduce them: %q, %q", a, b, gotA, gotB)
+	}
+	equal := 0
+	for _, e := range edits {
+		if e.op == ' ' {
+			equal++
+		}
+	}
+	if want := lcs(a, b); equal != want {
+		t.Errorf("diff of %q and %q keeps %d lines, want %d", a, b, equal, want)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{name: "equal", a: "a\nb\n", b: "a\nb\n", want: ""},
+		{name: "equal without newline", a: "a\nb", b: "a\nb", want: ""},
+		{name: "changed line", a: "a\nb\nc\n", b: "a\nB\nc\n", want: "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+		{
+			name: "newline added",
+			a:    "a\nb",
+			b:    "a\nb\n",
+			want: "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name: "newline removed",
+			a:    "a\nb\n",
+			b:    "a\nb",
+			want: "@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := string(unifiedDiff("a.def", []byte(test.a), []byte(test.b)))
+			want := "--- a.def\n+++ a.def (formatted)\n" + test.want
+			if got != want {
+				t.Errorf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
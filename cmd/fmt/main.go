@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	so "github.com/kpawlik/superobject"
+)
+
+// stdinName is the name of stdin in lists and diffs
+const stdinName = "<stdin>"
+
+var (
+	list   bool
+	diff   bool
+	write  bool
+	backup = so.BackupNone
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [file.def | dir]...\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "Without files the definition is read from stdin.")
+		flag.PrintDefaults()
+	}
+	flag.BoolVar(&list, "l", false, "list files whose formatting differs from canonical style")
+	flag.BoolVar(&diff, "d", false, "display diffs instead of rewriting files")
+	flag.BoolVar(&write, "w", false, "write result to (source) file instead of stdout")
+	flag.Var(&backup, "backup", "Backup of rewritten files: none, bak or timestamp")
+}
+
+func main() {
+	// parsed in main, so tests of the package can use their own flags
+	flag.Parse()
+	diag := &so.Diagnostics{}
+	if flag.NArg() == 0 {
+		diag.Error(formatStdin())
+		os.Exit(diag.Report(os.Stderr))
+	}
+	for _, path := range so.ExpandDefPaths(flag.Args(), diag) {
+		diag.Error(formatFile(path))
+	}
+	os.Exit(diag.Report(os.Stderr))
+}
+
+// formatStdin formats definition from stdin, -l and -d work as for files
+func formatStdin() (err error) {
+	var src, res []byte
+	if write {
+		return errors.New("-w can not be used with stdin")
+	}
+	if src, err = io.ReadAll(os.Stdin); err != nil {
+		return
+	}
+	if res, err = so.FormatSource(src); err != nil {
+		return
+	}
+	changed := !bytes.Equal(src, res)
+	if list && changed {
+		fmt.Println(stdinName)
+	}
+	if diff && changed {
+		_, err = os.Stdout.Write(unifiedDiff(stdinName, src, res))
+	}
+	if !list && !diff {
+		_, err = os.Stdout.Write(res)
+	}
+	return
+}
+
+func formatFile(path string) (err error) {
+	var (
+		def      *so.FeatureDef
+		src, res []byte
+	)
+	if src, err = os.ReadFile(path); err != nil {
+		return
+	}
+	if def, err = so.ReadDefFile(path); err != nil {
+		return
+	}
+	if res, err = so.Format(def); err != nil {
+		return
+	}
+	changed := !bytes.Equal(src, res)
+	if list && changed {
+		fmt.Println(path)
+	}
+	if diff && changed {
+		if _, err = os.Stdout.Write(unifiedDiff(path, src, res)); err != nil {
+			return
+		}
+	}
+	if write && changed {
+		return so.WriteDefFile(path, def, so.WriteOptions{Backup: backup})
+	}
+	if !list && !diff && !write {
+		_, err = os.Stdout.Write(res)
+	}
+	return
+}
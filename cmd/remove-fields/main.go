@@ -1,87 +1,76 @@
 package main
 
 import (
-	"encoding/csv"
+	"errors"
 	"flag"
-	"io"
+	"fmt"
 	"os"
 	"path/filepath"
-	"slices"
-	"strings"
 
-	"github.com/kpawlik/om"
 	so "github.com/kpawlik/superobject"
+	"github.com/kpawlik/superobject/changeset"
 )
 
-var(
-	FeatureDir string
+var (
+	FeatureDir     string
 	FieldsDiffFile string
+	Backup         = so.BackupNone
+	Verbose        bool
 )
 
-
 func init() {
 	flag.StringVar(&FeatureDir, "target-dir", "", "Feature dir")
-	flag.StringVar(&FieldsDiffFile, "cmp-file", "", "Fields diff file")	
+	flag.StringVar(&FieldsDiffFile, "cmp-file", "", "Changeset file of compare-feature-defs, JSON or CSV")
+	flag.Var(&Backup, "backup", "Backup of modified def files: none, bak or timestamp")
+	flag.BoolVar(&Verbose, "v", false, "Print removed fields with rewritten references")
 	flag.Parse()
 }
 
 func main() {
-	csvContent, err := os.ReadFile(FieldsDiffFile)
-	so.HandleErr(err)
-	reader := strings.NewReader(string(csvContent))
-	csvReader := csv.NewReader(reader)
-	fieldsToRemove := make(map[string][]string)
-	for{
-		row, err  := csvReader.Read()
-		if err == io.EOF {
-			break
-		}
-		feature := row[0]
-		if feature == "" {
-			continue
-		}
-		field := row[1]
-		removed := row[2]
-		if removed != "removed" {
-			continue
-		}
-		if _, ok := fieldsToRemove[feature]; !ok {
-			fieldsToRemove[feature] = make([]string, 0)
+	diag := &so.Diagnostics{}
+	cs, err := changeset.ReadFile(FieldsDiffFile)
+	if diag.Error(err) {
+		os.Exit(diag.Report(os.Stderr))
+	}
+	fieldsToRemove := cs.Fields(changeset.Removed)
+	for _, feature := range cs.Features() {
+		if fields, ok := fieldsToRemove[feature]; ok {
+			featurePath := filepath.Join(FeatureDir, feature+".def")
+			removeFields(featurePath, fields, diag)
 		}
-		fieldsToRemove[feature] = append(fieldsToRemove[feature], field)
-	} 
-	for feature, fields := range fieldsToRemove {
-		featurePath := filepath.Join(FeatureDir, feature + ".def")
-		removeFields(featurePath, fields)
-
 	}
-	
+	os.Exit(diag.Report(os.Stderr))
 }
 
-func removeFields(featurePath string, fieldsToRemove []string) {
-	var (
-		fileContent []byte
-		err         error
-	)
-	fileContent, err = os.ReadFile(featurePath)
-	so.HandleErr(err)
-	oMap := om.NewOrderedMap()
-	err = oMap.UnmarshalJSON(fileContent)
-	so.HandleErr(err)
-	featureFields:= oMap.Map["fields"].([]any)
-	newFields := make([]any, 0)
-	for _, featureField := range featureFields {
-		fieldName := featureField.(*om.OrderedMap).Map["name"].(string)
-		if slices.Contains(fieldsToRemove, fieldName) {
+// removeFields removes fields and references to them from the def. The def is not written
+// when a reference to any of the fields can not be rewritten safely.
+func removeFields(featurePath string, fieldsToRemove []string, diag *so.Diagnostics) {
+	featureDef, err := so.ReadDefFile(featurePath)
+	if diag.Error(err) {
+		return
+	}
+	blocked := false
+	for _, fieldName := range fieldsToRemove {
+		removal, err := featureDef.RemoveFieldCascade(fieldName)
+		switch {
+		case errors.Is(err, so.ErrFieldNotFound):
+			// field already removed, nothing to do
+			diag.Warning(err)
 			continue
+		case diag.Error(err):
+			blocked = true
+			continue
+		}
+		if Verbose {
+			fmt.Printf("%s: removed %s\n", featureDef.Name(), fieldName)
+			for _, ref := range removal.References {
+				fmt.Printf("  %s\n", ref)
+			}
 		}
-		newFields = append(newFields, featureField)
 	}
-	oMap.Map["fields"] = newFields
-	fileContent, err = oMap.MarshalIndent("    ")
-	so.HandleErr(err)
-	err = os.WriteFile(featurePath, fileContent, 0644)
-	so.HandleErr(err)
-
-	
-}
\ No newline at end of file
+	if blocked {
+		diag.Errorf("%s: not written, references to removed fields must be fixed first", featurePath)
+		return
+	}
+	diag.Error(so.WriteDefFile(featurePath, featureDef, so.WriteOptions{Backup: Backup}))
+}
@@ -1,115 +1,186 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
 
-	"github.com/kpawlik/om"
 	so "github.com/kpawlik/superobject"
 )
 
+var (
+	soSource           string
+	soCompose          string
+	soDest             string
+	excludedTypes      string
+	calcAttributes     string
+	groupName          string
+	methodTemplatePath string
+	methodsPath        string
+	enumDir            string
+	backup             = so.BackupNone
+	rules              so.RuleSet
+	keepStale          bool
+	verbose            bool
+)
+
+// ruleFlag adds rules with given action, include and exclude flags share one list, so order of flags is kept
+type ruleFlag so.RuleAction
+
+func (f ruleFlag) String() string {
+	return ""
+}
+
+func (f ruleFlag) Set(value string) error {
+	rule, err := so.ParseFieldRule(string(f) + " " + value)
+	if err != nil {
+		return err
+	}
+	rules = append(rules, rule)
+	return nil
+}
 
 func init() {
-	var(
-		soSource string
-		soCompose string
-		soDest string
-	)
 	flag.StringVar(&soSource, "source", "", "Path to source superobject def file")
 	flag.StringVar(&soCompose, "compose", "", "Path to compose superobject def file")
 	flag.StringVar(&soDest, "dest", "", "Path to destination of superobject with combined fields. Output file will be created if it does not exist")
+	flag.StringVar(&excludedTypes, "excluded-types", strings.Join(so.DefaultExcludedFields, ","), "Comma separated list of field types which are not copied from compose def")
+	flag.StringVar(&calcAttributes, "calc-attributes", strings.Join(so.DefaultCalcAttributes, ","), "Comma separated list of attributes copied from compose fields to calculated fields")
+	flag.Var(ruleFlag(so.RuleInclude), "include", "Include compose fields matching `matcher:pattern` (name:<glob>, regex:<regexp>, kind:<type>, attr:<key>[=<value>]). Can be repeated, last matching rule wins")
+	flag.Var(ruleFlag(so.RuleExclude), "exclude", "Exclude compose fields matching `matcher:pattern`. Can be repeated, last matching rule wins")
+	flag.BoolVar(&keepStale, "keep-stale", false, "Keep calculated fields whose source field no longer exists in the component or is excluded. Default is to remove them")
+	flag.BoolVar(&verbose, "v", false, "Print excluded compose fields with the rule which excluded them")
+	flag.StringVar(&groupName, "group", "", "Name of the group with compose fields. Default is external name of compose def")
+	flag.StringVar(&methodTemplatePath, "method-template", "", "Path to Go template file used to generate methods of calculated fields")
+	flag.StringVar(&enumDir, "enum-dir", "", "Dir where enumerators used by calculated fields are copied from dirs of compose and source defs. Default is dir of dest")
+	flag.Var(&backup, "backup", "Backup of overwritten files: none, bak or timestamp")
+	flag.StringVar(&methodsPath, "methods", "", "Path to file with methods of all calculated fields of dest, \"-\" for stdout. Default is <dest>_methods.txt")
 	flag.Parse()
 	if soSource == "" || soCompose == "" || soDest == "" {
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-}	
+}
 
-func readDef(path string) (def *om.OrderedMap, err error){
-	var file *os.File
-	file, err = os.OpenFile(path, os.O_RDWR, 0644)
-	if err != nil {
-		return
+// newComposer returns composer configured from command line flags
+func newComposer() (composer *so.Composer, err error) {
+	composer = so.NewComposer()
+	composer.ExcludedTypes = splitList(excludedTypes)
+	composer.CalcAttributes = splitList(calcAttributes)
+	composer.Rules = rules
+	composer.KeepStale = keepStale
+	if groupName != "" {
+		composer.GroupName = func(*so.FeatureDef) string { return groupName }
+	}
+	if methodTemplatePath != "" {
+		if composer.MethodTemplate, err = template.ParseFiles(methodTemplatePath); err != nil {
+			err = fmt.Errorf("failed to read method template: %w", err)
+			return
+		}
 	}
-	defer file.Close()
-	if def, err = so.ReadFeatureDef(bufio.NewReader(file)); err != nil {
-		err = fmt.Errorf("failed to read feature definition from %s: %w", path, err)
-		return	
+	return
+}
+
+// splitList returns not empty items of comma separated list
+func splitList(list string) (items []string) {
+	items = []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
 	return
 }
 
 func main() {
 	var (
-		err error
-		source *om.OrderedMap
-		compose *om.OrderedMap
-		fieldsNames = []string{}
-		file *os.File
-
+		err      error
+		source   *so.FeatureDef
+		compose  *so.FeatureDef
+		result   *so.FeatureDef
+		report   *so.ComposeReport
+		composer *so.Composer
 	)
-	sourcePath := flag.CommandLine.Lookup("source").Value.String()
-	destPath := flag.CommandLine.Lookup("dest").Value.String()
-	composePath := flag.CommandLine.Lookup("compose").Value.String()
-	if source, err = readDef(sourcePath); err != nil {
+	if composer, err = newComposer(); err != nil {
 		log.Fatal(err)
 	}
-	// add default group if it does not exist
-	if !so.IsGroupExists(source, "Default"){
-		defaultGroupFields := so.GetFields(source, so.GeomExcludedFields)
-		defaultFields := make([]string, len(defaultGroupFields))
-		for i, field := range defaultGroupFields {
-			defaultFields[i] = field.Name
-		}
-		so.AddGroup(source, "Default", defaultFields)
+	if source, err = so.ReadDefFile(soSource); err != nil {
+		log.Fatal(err)
 	}
 	// read compose definition
-	if compose, err = readDef(composePath); err != nil {
+	if compose, err = so.ReadDefFile(soCompose); err != nil {
 		log.Fatal(err)
 	}
-	// buffer for methods body
-	methods := bytes.NewBuffer([]byte{})
-	fields := so.GetFields(compose, nil)
-	// add fields to source superobject and generate methods
-	for _, f := range fields {
-		fieldName := f.Name
-		featureName := f.FeatureName
-		calcFieldName := fmt.Sprintf("calc__%s__%s", featureName, fieldName)
-		if so.IsFieldExists(source, calcFieldName) {
-			so.UpdateField(source, calcFieldName, f.ExternalName, f.Type, f.Unit)
-			method := so.GetMethodBody(calcFieldName, featureName, fieldName)
-			methods.WriteString(method)
-		}else{
-			so.AddField(source, calcFieldName, f.ExternalName, f.Type, f.Unit)
-		}
-		fieldsNames = append(fieldsNames, calcFieldName)
+	if result, report, err = composer.Compose(source, compose); err != nil {
+		log.Fatal(err)
 	}
-	// add new fields group if needed
-	composeExternalName := compose.Map["external_name"].(string)
-	if so.IsGroupExists(source, composeExternalName) {
-		so.UpdateGroup(source, composeExternalName, fieldsNames)
-	}else{
-		so.AddGroup(source, composeExternalName, fieldsNames)
+	for _, component := range report.Components {
+		for _, field := range component.Fields {
+			if field.TypeChange == so.TypeChangeLossy {
+				log.Printf("warning: lossy type change of field %s", field.Name)
+			}
+		}
+		for _, field := range component.Fields {
+			switch field.Action {
+			case so.FieldRemoved:
+				log.Printf("%s: removed stale field %s", component.Component, field.Name)
+			case so.FieldStale:
+				log.Printf("%s: kept stale field %s", component.Component, field.Name)
+			}
+		}
+		if verbose {
+			for _, excluded := range component.Excluded {
+				log.Printf("%s.%s excluded by rule: %s", component.Component, excluded.Name, excluded.Rule)
+			}
+		}
+		log.Printf("%s: %d added, %d updated, %d unchanged, %d removed fields in group %s",
+			component.Component, component.Count(so.FieldAdded), component.Count(so.FieldUpdated),
+			component.Count(so.FieldUnchanged), component.Count(so.FieldRemoved), component.Group)
 	}
 	// write new superobject definition to file
-	file, err = os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE, 0644)
+	writeOptions := so.WriteOptions{Backup: backup}
+	if err = so.WriteDefFile(soDest, result, writeOptions); err != nil {
+		log.Fatal(err)
+	}
+	// lock of the source records components composed before
+	lock, err := so.ReadLockOrNew(so.LockPath(soSource), result.Name())
+	if err != nil {
+		log.Fatal(err)
+	}
+	lock.SetPath(so.LockPath(soDest))
+	lock.Update(report)
+	if err = lock.Write(writeOptions); err != nil {
+		log.Fatal(err)
+	}
+	// methods are rebuilt from the result, so the file is the same after each run
+	methods, err := composer.RenderMethods(result)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch methodsPath {
+	case "-":
+		_, err = os.Stdout.Write(methods)
+	case "":
+		err = so.WriteFileAtomic(fmt.Sprintf("%s_methods.txt", soDest), methods, writeOptions)
+	default:
+		err = so.WriteFileAtomic(methodsPath, methods, writeOptions)
+	}
 	if err != nil {
-		log.Fatalf("failed to open file %s: %v", destPath, err)	
-	}
-	defer file.Close()
-	so.WriteFeatureDef(bufio.NewWriter(file), source)
-	// write methods to file
-	methodsPath := fmt.Sprintf("%s_methods.txt", destPath)
-	file, err = os.OpenFile(methodsPath, os.O_APPEND|os.O_WRONLY, 0644)
-	if os.IsNotExist(err){
-		os.WriteFile(methodsPath, methods.Bytes(), 0644)
-	}else{
-		file.Write(methods.Bytes())
-		file.Close()
-	}
-}
\ No newline at end of file
+		log.Fatalf("failed to write methods: %v", err)
+	}
+	// enumerators of calculated fields must be available next to the super object
+	diag := &so.Diagnostics{}
+	if enumDir == "" {
+		enumDir = filepath.Dir(soDest)
+	}
+	enums := composer.CopyEnums(result, []string{filepath.Dir(soCompose), filepath.Dir(soSource)}, enumDir, writeOptions, diag)
+	if len(enums.Copied) > 0 {
+		log.Printf("enumerators copied to %s: %s", enumDir, strings.Join(enums.Copied, ", "))
+	}
+	os.Exit(diag.Report(os.Stderr))
+}
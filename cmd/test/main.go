@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"os"
@@ -10,31 +11,38 @@ import (
 
 func main() {
 	soName := "eo_connector_point_inst"
-	sObj,err := so.ReadFeatureDef(fmt.Sprintf("test/%s.def", soName))
+	sObj, err := so.ReadDefFile(fmt.Sprintf("test/%s.def", soName))
 	if err != nil {
 		panic(err)
 	}
 	composeName := "eo_cable"
-	co, err:= so.ReadFeatureDef(fmt.Sprintf("test/%s.def", composeName))
-	if err != nil {	
+	co, err := so.ReadDefFile(fmt.Sprintf("test/%s.def", composeName))
+	if err != nil {
 		panic(err)
 	}
 	methods := bytes.NewBuffer([]byte{})
-	fields := so.GetFields(co)
+	fields := so.ListFields(co, nil)
 	for _, f := range fields {
-		calcFieldName := fmt.Sprintf("calc__%s__%s", f["feature_name"], f["name"])
-		so.AddField(sObj, calcFieldName, f["external_name"], f["type"])
-		method := so.GetMethodBody(calcFieldName, f["feature_name"], f["name"])
+		calcFieldName := fmt.Sprintf("calc__%s__%s", f.FeatureName, f.Name)
+		so.AddField(sObj.Raw(), calcFieldName, f.ExternalName, f.Type, f.Unit)
+		method := so.GetMethodBody(calcFieldName, f.FeatureName, f.Name)
 		methods.WriteString(method)
 	}
-	so.WriteFeatureDef(fmt.Sprintf("test/%s_result.def", soName), sObj)
+	file, err := os.Create(fmt.Sprintf("test/%s_result.def", soName))
+	if err != nil {
+		panic(err)
+	}
+	writer := bufio.NewWriter(file)
+	so.WriteFeatureDef(writer, sObj.Raw())
+	writer.Flush()
+	file.Close()
 	methodsFile := fmt.Sprintf("test/%s_methods.txt", soName)
-	file, err := os.OpenFile(methodsFile, os.O_APPEND|os.O_WRONLY, 0644)
-	if os.IsNotExist(err){
+	file, err = os.OpenFile(methodsFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if os.IsNotExist(err) {
 		os.WriteFile(fmt.Sprintf("test/%s_methods.txt", soName), methods.Bytes(), 0644)
-	}else{
+	} else {
 		file.Write(methods.Bytes())
 		file.Close()
 	}
-	
+
 }
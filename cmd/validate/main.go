@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	so "github.com/kpawlik/superobject"
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [file.def | dir]...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func main() {
+	var diag so.Diagnostics
+	for _, path := range so.ExpandDefPaths(flag.Args(), &diag) {
+		def, err := so.ReadDefFile(path)
+		if diag.Error(err) {
+			continue
+		}
+		for _, err := range so.Validate(def) {
+			if err.Warning {
+				diag.Warning(err)
+			} else {
+				diag.Error(err)
+			}
+		}
+	}
+	os.Exit(diag.Report(os.Stdout))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	so "github.com/kpawlik/superobject"
+)
+
+var jsonOutput bool
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [file.def | dir]...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.BoolVar(&jsonOutput, "json", false, "Print result as JSON")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// result of verification of one super object
+type result struct {
+	Feature    string               `json:"feature"`
+	Lock       string               `json:"lock"`
+	Components []so.ComponentStatus `json:"components"`
+}
+
+func main() {
+	var (
+		diag    so.Diagnostics
+		results = []result{}
+		changed bool
+	)
+	for _, path := range so.ExpandDefPaths(flag.Args(), &diag) {
+		lockPath := so.LockPath(path)
+		if _, err := os.Stat(lockPath); err != nil {
+			// not composed super object, e.g. component def in the same dir
+			continue
+		}
+		lock, err := so.ReadLock(lockPath)
+		if diag.Error(err) {
+			continue
+		}
+		res := result{Feature: lock.Feature, Lock: lockPath, Components: lock.Verify(&diag)}
+		for _, status := range res.Components {
+			changed = changed || status.Changed
+		}
+		results = append(results, res)
+	}
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", so.FormatIndent)
+		diag.Error(encoder.Encode(results))
+	} else {
+		for _, res := range results {
+			for _, status := range res.Components {
+				if !status.Changed {
+					fmt.Printf("%s: %s: ok\n", res.Feature, status.Component)
+					continue
+				}
+				fmt.Printf("%s: %s: changed %s\n", res.Feature, status.Component, status.Path)
+				if len(status.Removed) > 0 {
+					fmt.Printf("    removed fields: %s\n", strings.Join(status.Removed, ", "))
+				}
+				if len(status.Added) > 0 {
+					fmt.Printf("    new fields: %s\n", strings.Join(status.Added, ", "))
+				}
+			}
+		}
+	}
+	if code := diag.Report(os.Stderr); code != 0 {
+		os.Exit(code)
+	}
+	if changed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,269 @@
+package superobject
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/kpawlik/om"
+)
+
+// DiffState says how an item differs between two definitions
+type DiffState string
+
+const (
+	DiffAdded   DiffState = "added"
+	DiffRemoved DiffState = "removed"
+	DiffChanged DiffState = "changed"
+)
+
+const (
+	// SectionFields is the section of field definitions
+	SectionFields = "fields"
+	// SectionFeature is used for differences of whole features, e.g. feature which exists only in one version
+	SectionFeature = "feature"
+)
+
+// ItemDiff is a difference in one section of a feature definition.
+// Sections are top-level keys of the definition, e.g. "groups", "title" or "editable".
+// Sections which are lists of objects with names, like "fields", "groups" or "filters",
+// are compared item by item matched by name. Items without names, like searches and queries, are matched by value.
+type ItemDiff struct {
+	Section string `json:"section"`
+	// Name of the list item, empty if the whole section differs
+	Name  string    `json:"name,omitempty"`
+	State DiffState `json:"state"`
+	// Old is the value in the first definition, New in the second one. nil if the value does not exist.
+	Old any `json:"old,omitempty"`
+	New any `json:"new,omitempty"`
+	// Changes are differences of changed items, see DeepDiff
+	Changes []AttrDiff `json:"changes,omitempty"`
+}
+
+// AttrDiff is a difference of one attribute found by DeepDiff
+type AttrDiff struct {
+	// Path of the attribute, e.g. "unit" or "enum_values[3].value". Empty for the compared value itself.
+	Path  string    `json:"path"`
+	State DiffState `json:"state"`
+	Old   any       `json:"old,omitempty"`
+	New   any       `json:"new,omitempty"`
+}
+
+func (d AttrDiff) String() string {
+	var change string
+	switch d.State {
+	case DiffAdded:
+		change = "added " + formatJSON(d.New)
+	case DiffRemoved:
+		change = "removed " + formatJSON(d.Old)
+	default:
+		change = fmt.Sprintf("%s -> %s", formatJSON(d.Old), formatJSON(d.New))
+	}
+	if d.Path == "" {
+		return change
+	}
+	return d.Path + ": " + change
+}
+
+// ItemKeys are keys used to match items of lists, the first key which all items have is used
+var ItemKeys = []string{"name", "value"}
+
+// CompareDefs compares all sections of two feature definitions.
+// Differences are returned in order of sections and items in def1, followed by ones only in def2.
+func CompareDefs(def1, def2 *FeatureDef) (diffs []ItemDiff) {
+	for _, section := range unionKeys(def1.m, def2.m) {
+		diffs = append(diffs, compareSection(section, def1.m, def2.m)...)
+	}
+	return
+}
+
+// compareSection compares values of the top-level key
+func compareSection(section string, m1, m2 *om.OrderedMap) (diffs []ItemDiff) {
+	value1, ok1 := m1.Map[section]
+	value2, ok2 := m2.Map[section]
+	switch {
+	case ok1 && !ok2:
+		return []ItemDiff{{Section: section, State: DiffRemoved, Old: value1}}
+	case !ok1 && ok2:
+		return []ItemDiff{{Section: section, State: DiffAdded, New: value2}}
+	case len(DeepDiff(value1, value2)) == 0:
+		return nil
+	}
+	var items1, items2 []*om.OrderedMap
+	key := slices.IndexFunc(ItemKeys, func(key string) bool {
+		var ok1, ok2 bool
+		items1, ok1 = keyedItems(value1, key)
+		items2, ok2 = keyedItems(value2, key)
+		return ok1 && ok2
+	})
+	if key < 0 {
+		return []ItemDiff{{Section: section, State: DiffChanged, Old: value1, New: value2, Changes: DeepDiff(value1, value2)}}
+	}
+	itemKey := ItemKeys[key]
+	for _, item1 := range items1 {
+		name := item1.Map[itemKey].(string)
+		i := slices.IndexFunc(items2, func(item2 *om.OrderedMap) bool { return item2.Map[itemKey] == name })
+		switch {
+		case i < 0:
+			diffs = append(diffs, ItemDiff{Section: section, Name: name, State: DiffRemoved, Old: item1})
+		default:
+			if changes := DeepDiff(item1, items2[i]); len(changes) > 0 {
+				diffs = append(diffs, ItemDiff{Section: section, Name: name, State: DiffChanged, Old: item1, New: items2[i], Changes: changes})
+			}
+		}
+	}
+	for _, item2 := range items2 {
+		name := item2.Map[itemKey].(string)
+		if !slices.ContainsFunc(items1, func(item1 *om.OrderedMap) bool { return item1.Map[itemKey] == name }) {
+			diffs = append(diffs, ItemDiff{Section: section, Name: name, State: DiffAdded, New: item2})
+		}
+	}
+	return
+}
+
+// keyedItems returns items of the list if it is a list of objects with unique string values of the key
+func keyedItems(value any, key string) (items []*om.OrderedMap, ok bool) {
+	list, ok := value.([]any)
+	if !ok {
+		return nil, false
+	}
+	names := map[string]bool{}
+	for _, item := range list {
+		m, isMap := item.(*om.OrderedMap)
+		if !isMap {
+			return nil, false
+		}
+		name, isString := m.Map[key].(string)
+		if !isString || names[name] {
+			return nil, false
+		}
+		names[name] = true
+		items = append(items, m)
+	}
+	return items, true
+}
+
+// DeepDiff compares two values of a definition of any JSON type. Objects are compared key by key and
+// lists item by item, so the path of each difference is reported. Attribute which exists only in one
+// value is reported as added or removed. Numbers are equal when they have the same value, e.g. 1 and 1.0.
+func DeepDiff(value1, value2 any) []AttrDiff {
+	return deepDiff("", value1, value2, nil)
+}
+
+func deepDiff(path string, value1, value2 any, diffs []AttrDiff) []AttrDiff {
+	switch v1 := value1.(type) {
+	case *om.OrderedMap:
+		v2, ok := value2.(*om.OrderedMap)
+		if !ok {
+			break
+		}
+		for _, key := range unionKeys(v1, v2) {
+			item1, has1 := v1.Map[key]
+			item2, has2 := v2.Map[key]
+			keyPath := joinPath(path, key)
+			switch {
+			case !has2:
+				diffs = append(diffs, AttrDiff{Path: keyPath, State: DiffRemoved, Old: item1})
+			case !has1:
+				diffs = append(diffs, AttrDiff{Path: keyPath, State: DiffAdded, New: item2})
+			default:
+				diffs = deepDiff(keyPath, item1, item2, diffs)
+			}
+		}
+		return diffs
+	case []any:
+		v2, ok := value2.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < max(len(v1), len(v2)); i++ {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(v2):
+				diffs = append(diffs, AttrDiff{Path: itemPath, State: DiffRemoved, Old: v1[i]})
+			case i >= len(v1):
+				diffs = append(diffs, AttrDiff{Path: itemPath, State: DiffAdded, New: v2[i]})
+			default:
+				diffs = deepDiff(itemPath, v1[i], v2[i], diffs)
+			}
+		}
+		return diffs
+	case []string:
+		list := make([]any, len(v1))
+		for i, item := range v1 {
+			list[i] = item
+		}
+		return deepDiff(path, list, value2, diffs)
+	case json.Number:
+		if v2, ok := value2.(json.Number); ok && equalNumbers(v1, v2) {
+			return diffs
+		}
+	}
+	if list, ok := value2.([]string); ok {
+		items := make([]any, len(list))
+		for i, item := range list {
+			items[i] = item
+		}
+		return deepDiff(path, value1, items, diffs)
+	}
+	if !reflect.DeepEqual(value1, value2) {
+		diffs = append(diffs, AttrDiff{Path: path, State: DiffChanged, Old: value1, New: value2})
+	}
+	return diffs
+}
+
+// joinPath returns path of the key of the object at path
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func equalNumbers(n1, n2 json.Number) bool {
+	if n1 == n2 {
+		return true
+	}
+	f1, err1 := n1.Float64()
+	f2, err2 := n2.Float64()
+	return err1 == nil && err2 == nil && f1 == f2
+}
+
+// FormatChanges returns changes as text separated by "; "
+func FormatChanges(changes []AttrDiff) string {
+	lines := make([]string, len(changes))
+	for i, change := range changes {
+		lines[i] = change.String()
+	}
+	return strings.Join(lines, "; ")
+}
+
+// unionKeys returns keys of m1 followed by keys only in m2
+func unionKeys(m1, m2 *om.OrderedMap) []string {
+	keys := slices.Clone(m1.Keys)
+	for _, key := range m2.Keys {
+		if _, ok := m1.Map[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// FormatValue returns compact JSON of a value of a definition, strings are returned without quotes
+func FormatValue(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return formatJSON(value)
+}
+
+// formatJSON returns compact JSON of a value of a definition
+func formatJSON(value any) string {
+	data, err := MarshalValue(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,158 @@
+package superobject
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestCompareDefs(t *testing.T) {
+	tests := []struct {
+		name       string
+		def1, def2 string
+		want       []string
+	}{
+		{
+			name: "equal",
+			def1: `{"name": "a", "title": "{id}", "groups": [{"name": "G", "fields": ["id"]}]}`,
+			def2: `{"name": "a", "title": "{id}", "groups": [{"name": "G", "fields": ["id"]}]}`,
+		},
+		{
+			name: "scalar keys",
+			def1: `{"name": "a", "title": "{id}", "editable": true}`,
+			def2: `{"name": "a", "title": "{name}", "min_select": 1}`,
+			want: []string{`title changed [""]`, "editable removed", "min_select added"},
+		},
+		{
+			name: "fields by name",
+			def1: `{"name": "a", "fields": [{"name": "id", "type": "integer"}, {"name": "b", "type": "string"}]}`,
+			def2: `{"name": "a", "fields": [{"name": "c", "type": "string"}, {"name": "id", "type": "double", "unit": "m"}]}`,
+			want: []string{`fields id changed ["type" "unit"]`, "fields b removed", "fields c added"},
+		},
+		{
+			name: "groups by name",
+			def1: `{"name": "a", "groups": [{"name": "G1", "fields": ["id"]}, {"name": "G2", "fields": ["id"]}]}`,
+			def2: `{"name": "a", "groups": [{"name": "G3", "fields": ["id"]}, {"name": "G1", "fields": ["id"], "expanded": true}]}`,
+			want: []string{`groups G1 changed ["expanded"]`, "groups G2 removed", "groups G3 added"},
+		},
+		{
+			name: "searches by value",
+			def1: `{"name": "a", "searches": [{"value": "{id}", "description": "Id"}, {"value": "{name}"}]}`,
+			def2: `{"name": "a", "searches": [{"value": "{id}", "description": "Identifier"}, {"value": "{label}"}]}`,
+			want: []string{`searches {id} changed ["description"]`, "searches {name} removed", "searches {label} added"},
+		},
+		{
+			name: "queries by value",
+			def1: `{"name": "a", "queries": [{"value": "HS cables", "filter": "voltage = 10"}]}`,
+			def2: `{"name": "a", "queries": [{"value": "HS cables", "filter": "voltage = 20"}, {"value": "LS cables"}]}`,
+			want: []string{`queries HS cables changed ["filter"]`, "queries LS cables added"},
+		},
+		{
+			name: "list without names",
+			def1: `{"name": "a", "layers": ["l1", "l2"]}`,
+			def2: `{"name": "a", "layers": ["l1"]}`,
+			want: []string{`layers changed ["[1]"]`},
+		},
+		{
+			name: "duplicate names",
+			def1: `{"name": "a", "groups": [{"name": "G"}, {"name": "G"}]}`,
+			def2: `{"name": "a", "groups": [{"name": "G"}]}`,
+			want: []string{`groups changed ["[1]"]`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, diff := range CompareDefs(mustParseDef(t, tt.def1), mustParseDef(t, tt.def2)) {
+				s := diff.Section
+				if diff.Name != "" {
+					s += " " + diff.Name
+				}
+				s += " " + string(diff.State)
+				var paths []string
+				for _, change := range diff.Changes {
+					paths = append(paths, change.Path)
+				}
+				if paths != nil {
+					s += fmt.Sprintf(" %q", paths)
+				}
+				got = append(got, s)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeepDiff(t *testing.T) {
+	tests := []struct {
+		name           string
+		value1, value2 string
+		want           []string
+	}{
+		{name: "equal numbers", value1: `{"a": 1, "b": [1.0]}`, value2: `{"a": 1.0, "b": [1]}`},
+		{
+			name:   "attribute added and removed",
+			value1: `{"unit": "kV", "mandatory": true}`,
+			value2: `{"unit": "V", "indexed": true}`,
+			want:   []string{`unit changed "kV" "V"`, `mandatory removed true <nil>`, `indexed added <nil> true`},
+		},
+		{
+			name:   "path of list item",
+			value1: `{"enum_values": [{"value": "a"}, {"value": "b"}, {"value": "c"}, {"value": "d"}]}`,
+			value2: `{"enum_values": [{"value": "a"}, {"value": "b"}, {"value": "c"}, {"value": "e"}, {"value": "f"}]}`,
+			want:   []string{`enum_values[3].value changed "d" "e"`, `enum_values[4] added <nil> {"value":"f"}`},
+		},
+		{
+			name:   "list item removed",
+			value1: `{"validators": ["a", "b"]}`,
+			value2: `{"validators": ["a"]}`,
+			want:   []string{`validators[1] removed "b" <nil>`},
+		},
+		{
+			name:   "mixed types",
+			value1: `{"a": {"x": 1}, "b": [1], "c": "1", "d": null, "e": 1}`,
+			value2: `{"a": [1], "b": {"x": 1}, "c": 1, "d": {"x": 1}, "e": false}`,
+			want: []string{
+				`a changed {"x":1} [1]`,
+				`b changed [1] {"x":1}`,
+				`c changed "1" 1`,
+				`d changed <nil> {"x":1}`,
+				`e changed 1 false`,
+			},
+		},
+		{name: "scalar", value1: `"a"`, value2: `"b"`, want: []string{` changed "a" "b"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := mustParseDef(t, fmt.Sprintf(`{"value1": %s, "value2": %s}`, tt.value1, tt.value2))
+			var got []string
+			for _, diff := range DeepDiff(def.Get("value1"), def.Get("value2")) {
+				got = append(got, fmt.Sprintf("%s %s %s %s", diff.Path, diff.State, formatDiffValue(diff.Old), formatDiffValue(diff.New)))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeepDiffStringList(t *testing.T) {
+	def := mustParseDef(t, `{"fields": ["a", "c"]}`)
+	diffs := DeepDiff([]string{"a", "b"}, def.Get("fields"))
+	if len(diffs) != 1 || diffs[0].Path != "[1]" || diffs[0].State != DiffChanged {
+		t.Errorf("got %v, want change of [1]", diffs)
+	}
+	if diffs = DeepDiff(def.Get("fields"), []string{"a", "c"}); len(diffs) != 0 {
+		t.Errorf("got %v, want no differences", diffs)
+	}
+}
+
+// formatDiffValue returns compact JSON of the value, <nil> for null or missing value
+func formatDiffValue(value any) string {
+	if value == nil {
+		return "<nil>"
+	}
+	return formatJSON(value)
+}
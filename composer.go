@@ -0,0 +1,354 @@
+package superobject
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// NamingScheme builds names of calculated fields of a super object from
+// component feature and field names, and reverses them.
+type NamingScheme interface {
+	// CalcFieldName returns the name of the calculated field for the component field
+	CalcFieldName(featureName string, fieldName string) string
+	// ParseCalcFieldName returns component feature and field of the calculated field.
+	// ok is false if the name is not a calculated field name.
+	ParseCalcFieldName(name string) (featureName string, fieldName string, ok bool)
+}
+
+// CalcNaming is the NamingScheme building names as <Prefix><feature><Separator><field>
+type CalcNaming struct {
+	Prefix    string
+	Separator string
+}
+
+// DefaultNaming builds names like calc__eo_cable__voltage
+var DefaultNaming = CalcNaming{Prefix: "calc__", Separator: "__"}
+
+func (n CalcNaming) CalcFieldName(featureName string, fieldName string) string {
+	return n.Prefix + featureName + n.Separator + fieldName
+}
+
+func (n CalcNaming) ParseCalcFieldName(name string) (featureName string, fieldName string, ok bool) {
+	rest, found := strings.CutPrefix(name, n.Prefix)
+	if !found {
+		return
+	}
+	featureName, fieldName, ok = strings.Cut(rest, n.Separator)
+	ok = ok && featureName != "" && fieldName != ""
+	return
+}
+
+var (
+	// DefaultCalcAttributes are attributes copied from component fields to calculated fields
+	DefaultCalcAttributes = []string{
+		"unit", "display_unit", "unit_scale", "display_format", "enum", "min_value", "max_value", "visible", "viewer_class",
+	}
+	// DroppedCalcAttributes make no sense on calculated fields, they are never copied and removed from calculated fields
+	DroppedCalcAttributes = []string{"mandatory", "indexed", "default", "generator", "key"}
+)
+
+// ComponentGroupName returns external name of the component, or its name if external name is not set
+func ComponentGroupName(component *FeatureDef) string {
+	if name := component.ExternalName(); name != "" {
+		return name
+	}
+	return component.Name()
+}
+
+// Composer composes super object definition from component definitions.
+// Use NewComposer to get composer with default options.
+type Composer struct {
+	// Naming builds names of calculated fields
+	Naming NamingScheme
+	// ExcludedTypes are types of component fields which are not copied to the super object.
+	// nil means DefaultExcludedFields.
+	ExcludedTypes []string
+	// Rules include or exclude component fields. They are applied after exclusion
+	// of myw_ system fields and ExcludedTypes, so they can include fields excluded by them.
+	Rules RuleSet
+	// CalcAttributes are attributes copied from component fields to calculated fields, besides
+	// external_name and type. Attributes missing in the component field are removed from the
+	// calculated field. DroppedCalcAttributes are never copied. nil means DefaultCalcAttributes.
+	CalcAttributes []string
+	// KeepStale keeps calculated fields whose source field no longer exists in the component
+	// or is excluded by rules or ExcludedTypes. By default they are removed from fields and
+	// groups of the super object.
+	KeepStale bool
+	// DefaultGroup is the name of the group created for the super object own fields
+	// when it does not exist yet. Empty disables the group.
+	DefaultGroup string
+	// GroupName returns name of the group with fields of the component
+	GroupName func(component *FeatureDef) string
+	// MethodTemplate renders JS method of a calculated field, executed with Method
+	MethodTemplate *template.Template
+}
+
+// FieldAction says what happened with a calculated field during composition
+type FieldAction string
+
+const (
+	FieldAdded     FieldAction = "added"
+	FieldUpdated   FieldAction = "updated"
+	FieldUnchanged FieldAction = "unchanged"
+	// FieldRemoved is a stale calculated field removed from the super object
+	FieldRemoved FieldAction = "removed"
+	// FieldStale is a stale calculated field kept because of Composer.KeepStale
+	FieldStale FieldAction = "stale"
+)
+
+// FieldChange describes one calculated field of the composed definition
+type FieldChange struct {
+	// Name of the calculated field
+	Name string `json:"name"`
+	// Source is the name of the field in the component
+	Source string      `json:"source"`
+	Action FieldAction `json:"action"`
+	// TypeChange is the effect of the type change for updated fields
+	TypeChange TypeChange `json:"type_change"`
+	// Groups the removed field was removed from
+	Groups []string `json:"groups,omitempty"`
+}
+
+// ComponentReport describes changes made by one component
+type ComponentReport struct {
+	Component string `json:"component"`
+	// Path and Hash of the component definition file, empty for definitions created in memory
+	Path       string        `json:"path,omitempty"`
+	Hash       string        `json:"hash,omitempty"`
+	Group      string        `json:"group"`
+	GroupAdded bool          `json:"group_added"`
+	Fields     []FieldChange `json:"fields"`
+	// Excluded are component fields not copied to the super object
+	Excluded []ExcludedField `json:"excluded,omitempty"`
+	// Rules used to select component fields, see Composer.FieldRules
+	Rules []string `json:"rules,omitempty"`
+}
+
+// Count returns number of fields with given action
+func (r *ComponentReport) Count(action FieldAction) (count int) {
+	for _, field := range r.Fields {
+		if field.Action == action {
+			count++
+		}
+	}
+	return
+}
+
+// ComposeReport describes changes made to the super object by Composer.Compose
+type ComposeReport struct {
+	Feature           string             `json:"feature"`
+	DefaultGroupAdded bool               `json:"default_group_added"`
+	Components        []*ComponentReport `json:"components"`
+}
+
+// NewComposer returns composer with the default options, the same as used by so-generator
+func NewComposer() *Composer {
+	return &Composer{
+		Naming:         DefaultNaming,
+		ExcludedTypes:  DefaultExcludedFields,
+		DefaultGroup:   "Default",
+		GroupName:      ComponentGroupName,
+		MethodTemplate: methodTemplate,
+	}
+}
+
+// Compose adds fields of components to the super object definition as calculated fields.
+// Existing calculated fields are updated and component groups are added or replaced.
+// The base definition is not modified, composed definition is returned as a new object.
+func (c *Composer) Compose(base *FeatureDef, components ...*FeatureDef) (def *FeatureDef, report *ComposeReport, err error) {
+	def = base.Clone()
+	report = &ComposeReport{Feature: def.Name()}
+	// add default group if it does not exist
+	if c.DefaultGroup != "" && def.Group(c.DefaultGroup) == nil {
+		var defaultFields []string
+		for _, field := range ListFields(def, GeomExcludedFields) {
+			defaultFields = append(defaultFields, field.Name)
+		}
+		def.AddGroup(NewGroupDef(c.DefaultGroup, defaultFields))
+		report.DefaultGroupAdded = true
+	}
+	for _, component := range components {
+		var componentReport *ComponentReport
+		if componentReport, err = c.composeComponent(def, component); err != nil {
+			return nil, nil, err
+		}
+		report.Components = append(report.Components, componentReport)
+	}
+	return
+}
+
+func (c *Composer) composeComponent(def *FeatureDef, component *FeatureDef) (report *ComponentReport, err error) {
+	featureName := component.Name()
+	if featureName == "" {
+		err = fmt.Errorf("component %s: missing name", component.Path)
+		return
+	}
+	report = &ComponentReport{Component: featureName, Path: component.Path, Hash: component.Hash, Group: c.GroupName(component)}
+	rules := c.FieldRules()
+	for _, rule := range rules {
+		report.Rules = append(report.Rules, rule.String())
+	}
+	fieldsNames := []string{}
+	for _, componentField := range component.Fields() {
+		if included, rule := rules.Match(componentField); !included {
+			report.Excluded = append(report.Excluded, ExcludedField{Name: componentField.Name(), Rule: rule.String()})
+			continue
+		}
+		calcFieldName := c.Naming.CalcFieldName(featureName, componentField.Name())
+		change := FieldChange{Name: calcFieldName, Source: componentField.Name(), Action: FieldAdded}
+		field := def.Field(calcFieldName)
+		if field == nil {
+			field = NewFieldDef(calcFieldName)
+			def.AddField(field)
+		} else {
+			change.Action = FieldUpdated
+			change.TypeChange = CompareTypeStrings(field.Type(), componentField.Type())
+		}
+		if !c.setCalcField(field, componentField) && change.Action == FieldUpdated {
+			change.Action = FieldUnchanged
+		}
+		report.Fields = append(report.Fields, change)
+		fieldsNames = append(fieldsNames, calcFieldName)
+	}
+	for _, change := range c.prune(def, component, fieldsNames) {
+		if change.Action == FieldStale {
+			fieldsNames = append(fieldsNames, change.Name)
+		}
+		report.Fields = append(report.Fields, change)
+	}
+	// add new fields group if needed
+	if group := def.Group(report.Group); group != nil {
+		group.SetFields(fieldsNames)
+	} else {
+		def.AddGroup(NewGroupDef(report.Group, fieldsNames))
+		report.GroupAdded = true
+	}
+	return
+}
+
+// prune removes stale calculated fields of the component, i.e. fields not in composed. Their source field
+// no longer exists in the component or it is excluded now by rules or ExcludedTypes.
+func (c *Composer) prune(def *FeatureDef, component *FeatureDef, composed []string) (changes []FieldChange) {
+	featureName := component.Name()
+	for _, field := range def.Fields() {
+		calcFeatureName, fieldName, ok := c.Naming.ParseCalcFieldName(field.Name())
+		if !ok || calcFeatureName != featureName || slices.Contains(composed, field.Name()) {
+			continue
+		}
+		change := FieldChange{Name: field.Name(), Source: fieldName, Action: FieldStale}
+		if !c.KeepStale {
+			change.Action = FieldRemoved
+			def.RemoveField(field.Name())
+			change.Groups = def.RemoveFromGroups(field.Name())
+		}
+		changes = append(changes, change)
+	}
+	return
+}
+
+// FieldRules returns all rules used to select component fields: exclusion
+// of myw_ system fields, exclusion of ExcludedTypes and Rules.
+func (c *Composer) FieldRules() RuleSet {
+	excluded := c.ExcludedTypes
+	if excluded == nil {
+		excluded = DefaultExcludedFields
+	}
+	rules := RuleSet{SystemFieldsRule}
+	rules = append(rules, TypeRules(excluded)...)
+	return append(rules, c.Rules...)
+}
+
+// Attributes returns attributes copied from component fields to calculated fields
+func (c *Composer) Attributes() (attributes []string) {
+	attributes = c.CalcAttributes
+	if attributes == nil {
+		attributes = DefaultCalcAttributes
+	}
+	return slices.DeleteFunc(slices.Clone(attributes), func(key string) bool {
+		return slices.Contains(DroppedCalcAttributes, key) || key == "name" || key == "value"
+	})
+}
+
+// setCalcField sets attributes of calculated field from the component field.
+// Returns true if any attribute was changed.
+func (c *Composer) setCalcField(field *FieldDef, source *FieldDef) (changed bool) {
+	set := func(key string, value any) {
+		if !field.Has(key) || !reflect.DeepEqual(field.Get(key), value) {
+			field.Set(key, value)
+			changed = true
+		}
+	}
+	remove := func(key string) {
+		if field.Has(key) {
+			field.Delete(key)
+			changed = true
+		}
+	}
+	set("external_name", source.ExternalName())
+	set("type", source.Type())
+	set("value", fmt.Sprintf("method(%s)", field.Name()))
+	for _, key := range c.Attributes() {
+		if source.Has(key) {
+			set(key, cloneValue(source.Get(key)))
+		} else {
+			remove(key)
+		}
+	}
+	for _, key := range DroppedCalcAttributes {
+		remove(key)
+	}
+	return
+}
+
+// Methods returns one method for each calculated field of the definition with value "method(...)",
+// in the order of fields. Methods are built from the definition only, so the result is the same
+// no matter how many times the definition was composed.
+func (c *Composer) Methods(def *FeatureDef) (methods []Method) {
+	seen := map[string]bool{}
+	for _, field := range def.Fields() {
+		featureName, fieldName, ok := c.Naming.ParseCalcFieldName(field.Name())
+		if !ok {
+			continue
+		}
+		methodName, ok := field.MethodName()
+		if !ok || seen[methodName] {
+			continue
+		}
+		seen[methodName] = true
+		methods = append(methods, Method{
+			MethodName:  methodName,
+			FeatureName: featureName,
+			FieldName:   fieldName,
+		})
+	}
+	return
+}
+
+// RenderMethods renders JS methods of all calculated fields of the definition
+func (c *Composer) RenderMethods(def *FeatureDef) (methods []byte, err error) {
+	buff := bytes.NewBuffer([]byte{})
+	for _, method := range c.Methods(def) {
+		var body string
+		if body, err = c.MethodBody(method); err != nil {
+			return
+		}
+		buff.WriteString(body)
+	}
+	methods = buff.Bytes()
+	return
+}
+
+// MethodBody renders JS method of the calculated field
+func (c *Composer) MethodBody(method Method) (body string, err error) {
+	buff := bytes.NewBuffer([]byte{})
+	if err = c.MethodTemplate.Execute(buff, method); err != nil {
+		err = fmt.Errorf("failed to render method %s: %w", method.MethodName, err)
+		return
+	}
+	body = buff.String()
+	return
+}
@@ -0,0 +1,99 @@
+package superobject
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestComposeChanges(t *testing.T) {
+	const (
+		base = `{"name": "so", "fields": [{"name": "id", "type": "integer"}]}`
+		v1   = `{"name": "c", "external_name": "C", "fields": [
+			{"name": "a", "type": "string(10)"},
+			{"name": "b", "type": "string(10)"}]}`
+	)
+	tests := []struct {
+		name      string
+		component string
+		rules     []string
+		keepStale bool
+		actions   map[string]FieldAction
+		fields    []string
+	}{
+		{
+			name: "update and new field",
+			component: `{"name": "c", "external_name": "C", "fields": [
+				{"name": "a", "type": "string(20)"},
+				{"name": "b", "type": "string(10)"},
+				{"name": "n", "type": "integer"}]}`,
+			actions: map[string]FieldAction{"a": FieldUpdated, "b": FieldUnchanged, "n": FieldAdded},
+			fields:  []string{"calc__c__a", "calc__c__b", "calc__c__n"},
+		},
+		{
+			name:      "removed field",
+			component: `{"name": "c", "external_name": "C", "fields": [{"name": "a", "type": "string(10)"}]}`,
+			actions:   map[string]FieldAction{"a": FieldUnchanged, "b": FieldRemoved},
+			fields:    []string{"calc__c__a"},
+		},
+		{
+			name:      "excluded by rule",
+			component: v1,
+			rules:     []string{"exclude name:b"},
+			actions:   map[string]FieldAction{"a": FieldUnchanged, "b": FieldRemoved},
+			fields:    []string{"calc__c__a"},
+		},
+		{
+			name: "excluded by type",
+			component: `{"name": "c", "external_name": "C", "fields": [
+				{"name": "a", "type": "string(10)"},
+				{"name": "b", "type": "reference(d)"}]}`,
+			actions: map[string]FieldAction{"a": FieldUnchanged, "b": FieldRemoved},
+			fields:  []string{"calc__c__a"},
+		},
+		{
+			name:      "excluded and kept",
+			component: v1,
+			rules:     []string{"exclude name:b"},
+			keepStale: true,
+			actions:   map[string]FieldAction{"a": FieldUnchanged, "b": FieldStale},
+			fields:    []string{"calc__c__a", "calc__c__b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			composed, _, err := NewComposer().Compose(mustParseDef(t, base), mustParseDef(t, v1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			composer := NewComposer()
+			composer.KeepStale = tt.keepStale
+			if composer.Rules, err = ParseRuleSet(tt.rules); err != nil {
+				t.Fatal(err)
+			}
+			def, report, err := composer.Compose(composed, mustParseDef(t, tt.component))
+			if err != nil {
+				t.Fatal(err)
+			}
+			actions := map[string]FieldAction{}
+			for _, change := range report.Components[0].Fields {
+				actions[change.Source] = change.Action
+			}
+			if !maps.Equal(actions, tt.actions) {
+				t.Errorf("actions = %v, want %v", actions, tt.actions)
+			}
+			var calcFields []string
+			for _, name := range def.FieldNames() {
+				if _, _, ok := DefaultNaming.ParseCalcFieldName(name); ok {
+					calcFields = append(calcFields, name)
+				}
+			}
+			if !slices.Equal(calcFields, tt.fields) {
+				t.Errorf("calculated fields = %v, want %v", calcFields, tt.fields)
+			}
+			if group := def.Group("C").Fields(); !slices.Equal(group, tt.fields) {
+				t.Errorf("group fields = %v, want %v", group, tt.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package superobject
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// DecomposeReport describes changes made to the super object by Composer.Decompose
+type DecomposeReport struct {
+	Feature   string `json:"feature"`
+	Component string `json:"component"`
+	// Fields are removed calculated fields
+	Fields []string `json:"fields"`
+	// Groups are removed groups, left empty after removing the fields
+	Groups []string `json:"groups"`
+	// KeptGroups had calculated fields of the component, they are kept because other fields are left in them
+	KeptGroups []string `json:"kept_groups,omitempty"`
+	// Methods of removed fields
+	Methods []string `json:"methods"`
+}
+
+// Decompose removes all calculated fields of the component from the super object definition,
+// from fields and from groups. Groups left empty are removed, so is the component group.
+// Groups with other fields left, e.g. native fields added to the component group by hand,
+// are kept and reported in DecomposeReport.KeptGroups. Native fields of the super object are not changed.
+// Returns error wrapping ErrComponentNotFound if the definition has no calculated fields of the component.
+// The base definition is not modified, result is returned as a new object.
+func (c *Composer) Decompose(base *FeatureDef, component string) (def *FeatureDef, report *DecomposeReport, err error) {
+	def = base.Clone()
+	report = &DecomposeReport{Feature: def.Name(), Component: component}
+	var groups []string
+	for _, field := range def.Fields() {
+		featureName, _, ok := c.Naming.ParseCalcFieldName(field.Name())
+		if !ok || featureName != component {
+			continue
+		}
+		if methodName, ok := field.MethodName(); ok && !slices.Contains(report.Methods, methodName) {
+			report.Methods = append(report.Methods, methodName)
+		}
+		def.RemoveField(field.Name())
+		report.Fields = append(report.Fields, field.Name())
+		for _, group := range def.RemoveFromGroups(field.Name()) {
+			if !slices.Contains(groups, group) {
+				groups = append(groups, group)
+			}
+		}
+	}
+	if len(report.Fields) == 0 {
+		return nil, nil, fmt.Errorf("%s: component %s: %w", def.Name(), component, ErrComponentNotFound)
+	}
+	for _, name := range groups {
+		group := def.Group(name)
+		switch {
+		case group == nil:
+		case len(group.list("fields")) == 0:
+			def.RemoveGroup(name)
+			report.Groups = append(report.Groups, name)
+		default:
+			report.KeptGroups = append(report.KeptGroups, name)
+		}
+	}
+	return
+}
+
+// RemoveMethods removes JS methods with the given names, and the doc comments before them,
+// from generated methods or JS class source. Returns the source without the methods and names of removed methods.
+func RemoveMethods(src []byte, names []string) (res []byte, removed []string) {
+	res = src
+	for _, name := range names {
+		header := regexp.MustCompile(`(?m)^[ \t]*(?:async[ \t]+)?` + regexp.QuoteMeta(name) + `[ \t]*\([^)]*\)[ \t]*\{`)
+		loc := header.FindIndex(res)
+		if loc == nil {
+			continue
+		}
+		end := matchingBrace(res, loc[1]-1)
+		if end < 0 {
+			continue
+		}
+		start := methodStart(res, loc[0])
+		// remove rest of the line with closing brace
+		if i := bytes.IndexByte(res[end:], '\n'); i >= 0 {
+			end += i + 1
+		} else {
+			end = len(res)
+		}
+		res = append(res[:start:start], res[end:]...)
+		removed = append(removed, name)
+	}
+	return
+}
+
+// methodStart returns start of the method including its doc comment and one empty line before
+func methodStart(src []byte, start int) int {
+	before := bytes.TrimRight(src[:start], " \t\r\n")
+	if bytes.HasSuffix(before, []byte("*/")) {
+		if i := bytes.LastIndex(before, []byte("/*")); i >= 0 {
+			start = bytes.LastIndexByte(src[:i], '\n') + 1
+		}
+	}
+	// empty line before the method
+	if line := bytes.LastIndexByte(src[:max(start-1, 0)], '\n'); start > 0 && len(bytes.TrimSpace(src[line+1:start])) == 0 {
+		start = line + 1
+	}
+	return start
+}
+
+// matchingBrace returns position after the brace closing the one at open, or -1 if it is not closed.
+// Braces in strings and comments are skipped.
+func matchingBrace(src []byte, open int) int {
+	depth := 0
+	for i := open; i < len(src); i++ {
+		switch src[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		case '"', '\'', '`':
+			quote := src[i]
+			for i++; i < len(src) && src[i] != quote; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+			}
+		case '/':
+			switch {
+			case bytes.HasPrefix(src[i:], []byte("//")):
+				if j := bytes.IndexByte(src[i:], '\n'); j >= 0 {
+					i += j
+				} else {
+					i = len(src)
+				}
+			case bytes.HasPrefix(src[i:], []byte("/*")):
+				if j := bytes.Index(src[i+2:], []byte("*/")); j >= 0 {
+					i += j + 3
+				} else {
+					i = len(src)
+				}
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,59 @@
+package superobject
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestDecompose(t *testing.T) {
+	const def = `{"name": "so", "fields": [
+		{"name": "id", "type": "integer"},
+		{"name": "note", "type": "string"},
+		{"name": "calc__c__a", "type": "string", "value": "method(calc__c__a)"},
+		{"name": "calc__c__b", "type": "string", "value": "method(calc__c__b)"},
+		{"name": "calc__d__a", "type": "string", "value": "method(calc__d__a)"}],
+	"groups": [
+		{"name": "Default", "fields": ["id", "note"]},
+		{"name": "C", "fields": ["calc__c__a", "calc__c__b"]},
+		{"name": "D", "fields": ["calc__d__a", "note", "calc__c__a"]}]}`
+	tests := []struct {
+		component  string
+		err        error
+		fields     []string
+		groups     []string
+		keptGroups []string
+	}{
+		{component: "c", fields: []string{"calc__c__a", "calc__c__b"}, groups: []string{"C"}, keptGroups: []string{"D"}},
+		{component: "d", fields: []string{"calc__d__a"}, keptGroups: []string{"D"}},
+		{component: "e", err: ErrComponentNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.component, func(t *testing.T) {
+			base := mustParseDef(t, def)
+			result, report, err := NewComposer().Decompose(base, tt.component)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if !slices.Equal(report.Fields, tt.fields) || !slices.Equal(report.Groups, tt.groups) || !slices.Equal(report.KeptGroups, tt.keptGroups) {
+				t.Errorf("report = %+v, want fields %v, groups %v, kept groups %v", report, tt.fields, tt.groups, tt.keptGroups)
+			}
+			for _, name := range tt.fields {
+				if result.HasField(name) {
+					t.Errorf("field %s not removed", name)
+				}
+				if !base.HasField(name) {
+					t.Errorf("field %s removed from base", name)
+				}
+			}
+			for _, name := range tt.keptGroups {
+				if result.Group(name) == nil {
+					t.Errorf("group %s removed", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,519 @@
+package superobject
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/kpawlik/om"
+)
+
+var (
+	// value of calculated fields, e.g. "method(calc__eo_cable__name)"
+	methodValuePattern = regexp.MustCompile(`^method\(\s*([^()\s]+)\s*\)$`)
+)
+
+// Object is an ordered JSON object from a feature definition.
+// All values are kept in the underlying ordered map, so the order of keys
+// and attributes unknown to this package survive a read/write round trip.
+type Object struct {
+	m *om.OrderedMap
+}
+
+// Raw returns the underlying ordered map
+func (o Object) Raw() *om.OrderedMap {
+	return o.m
+}
+
+// Keys returns the object keys in their original order
+func (o Object) Keys() []string {
+	return slices.Clone(o.m.Keys)
+}
+
+// Has returns true if the object contains the key
+func (o Object) Has(key string) bool {
+	_, ok := o.m.Map[key]
+	return ok
+}
+
+// Get returns the raw value stored under the key or nil
+func (o Object) Get(key string) any {
+	return o.m.Map[key]
+}
+
+// String returns the value of the key if it is a string, otherwise ""
+func (o Object) String(key string) string {
+	value, _ := o.m.Map[key].(string)
+	return value
+}
+
+// Bool returns the value of the key if it is a boolean, otherwise false
+func (o Object) Bool(key string) bool {
+	value, _ := o.m.Map[key].(bool)
+	return value
+}
+
+// Set updates the value of the key or appends the key at the end of the object
+func (o Object) Set(key string, value any) {
+	o.m.Set(key, value)
+}
+
+// Delete removes the key and its value from the object
+func (o Object) Delete(key string) {
+	delete(o.m.Map, key)
+	o.m.Keys = slices.DeleteFunc(o.m.Keys, func(k string) bool { return k == key })
+}
+
+// list returns the value of the key as a list. Lists of strings
+// created in memory are converted to []any, so callers see one shape.
+func (o Object) list(key string) []any {
+	switch value := o.m.Map[key].(type) {
+	case []any:
+		return value
+	case []string:
+		list := make([]any, len(value))
+		for i, s := range value {
+			list[i] = s
+		}
+		return list
+	}
+	return nil
+}
+
+// FeatureDef is a typed view of a myWorld feature definition (.def file)
+type FeatureDef struct {
+	Object
+	// Path is the file the definition was read from. Empty for definitions created in memory.
+	Path string
+	// Hash of the file content when it was read, see FileHash
+	Hash string
+}
+
+// FieldDef is a single entry of the "fields" list of a feature definition
+type FieldDef struct {
+	Object
+}
+
+// GroupDef is a single entry of the "groups" list of a feature definition
+type GroupDef struct {
+	Object
+}
+
+// NewFeatureDef creates an empty feature definition with the given name
+func NewFeatureDef(name string) *FeatureDef {
+	def := WrapFeatureDef(om.NewOrderedMap())
+	def.Set("name", name)
+	def.Set("fields", []any{})
+	def.Set("groups", []any{})
+	return def
+}
+
+// WrapFeatureDef returns typed view of a feature definition read into an ordered map.
+// Changes made through the view are visible in the map and vice versa.
+func WrapFeatureDef(featureDef *om.OrderedMap) *FeatureDef {
+	return &FeatureDef{Object: Object{m: featureDef}}
+}
+
+// ParseFeatureDef parses the feature definition from JSON.
+// Returns *MalformedDefError if data is not a valid JSON object.
+func ParseFeatureDef(data []byte) (def *FeatureDef, err error) {
+	return parseFeatureDef("", data)
+}
+
+// ReadDefFile reads the feature definition from a file.
+// Returns *MalformedDefError if the file is not a valid JSON object.
+func ReadDefFile(path string) (def *FeatureDef, err error) {
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		err = fmt.Errorf("failed to read feature definition: %w", err)
+		return
+	}
+	if def, err = parseFeatureDef(path, data); err != nil {
+		return
+	}
+	def.Path = path
+	def.Hash = FileHash(data)
+	return
+}
+
+// ListDefFiles returns paths of all .def files in the directory, sorted by name
+func ListDefFiles(dir string) (paths []string, err error) {
+	var entries []os.DirEntry
+	if entries, err = os.ReadDir(dir); err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".def") {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+	}
+	return
+}
+
+// ExpandDefPaths returns given .def files and all .def files from given directories.
+// Paths which can not be read are recorded in diag and skipped.
+func ExpandDefPaths(args []string, diag *Diagnostics) (paths []string) {
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if diag.Error(err) {
+			continue
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		dirPaths, err := ListDefFiles(arg)
+		diag.Error(err)
+		paths = append(paths, dirPaths...)
+	}
+	return
+}
+
+func parseFeatureDef(path string, data []byte) (def *FeatureDef, err error) {
+	featureDef := om.NewOrderedMap()
+	if err = featureDef.UnmarshalJSON(data); err != nil {
+		// ordered map does not report position, find it with standard decoder
+		var (
+			value     any
+			syntaxErr *json.SyntaxError
+		)
+		jsonErr := json.Unmarshal(data, &value)
+		switch {
+		case errors.As(jsonErr, &syntaxErr):
+			// offset is reported after the invalid byte
+			err = newMalformedDefError(path, data, max(syntaxErr.Offset-1, 0), syntaxErr)
+		case jsonErr == nil && jsonTypeName(value) != "object":
+			err = newMalformedDefError(path, data, 0, &UnexpectedTypeError{Key: "(root)", Expected: "object", Got: value})
+		default:
+			err = newMalformedDefError(path, data, -1, err)
+		}
+		return
+	}
+	def = WrapFeatureDef(featureDef)
+	return
+}
+
+// jsonTypeName returns the JSON name of the type of a value
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number, float64, int:
+		return "number"
+	case []any, []string:
+		return "array"
+	case *om.OrderedMap, map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// MarshalJSON implements json.Marshaler
+func (d *FeatureDef) MarshalJSON() ([]byte, error) {
+	return d.m.MarshalJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *FeatureDef) UnmarshalJSON(data []byte) (err error) {
+	featureDef := om.NewOrderedMap()
+	if err = featureDef.UnmarshalJSON(data); err != nil {
+		return
+	}
+	d.m = featureDef
+	return
+}
+
+// Clone returns a deep copy of the feature definition
+func (d *FeatureDef) Clone() *FeatureDef {
+	return &FeatureDef{Object: Object{m: cloneValue(d.m).(*om.OrderedMap)}, Path: d.Path, Hash: d.Hash}
+}
+
+// Name returns the internal name of the feature
+func (d *FeatureDef) Name() string {
+	return d.String("name")
+}
+
+// ExternalName returns the display name of the feature
+func (d *FeatureDef) ExternalName() string {
+	return d.String("external_name")
+}
+
+// Fields returns the field definitions in definition order.
+// Entries of the "fields" list which are not objects are skipped.
+func (d *FeatureDef) Fields() (fields []*FieldDef) {
+	for _, item := range d.list("fields") {
+		if field, ok := item.(*om.OrderedMap); ok {
+			fields = append(fields, &FieldDef{Object: Object{m: field}})
+		}
+	}
+	return
+}
+
+// FieldNames returns the names of all fields in definition order
+func (d *FeatureDef) FieldNames() (names []string) {
+	for _, field := range d.Fields() {
+		names = append(names, field.Name())
+	}
+	return
+}
+
+// Field returns the field with the given name or nil if it does not exist
+func (d *FeatureDef) Field(name string) *FieldDef {
+	for _, field := range d.Fields() {
+		if field.Name() == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// HasField returns true if the field exists in the feature definition
+func (d *FeatureDef) HasField(name string) bool {
+	return d.Field(name) != nil
+}
+
+// AddField appends the field at the end of the "fields" list
+func (d *FeatureDef) AddField(field *FieldDef) {
+	d.Set("fields", append(d.list("fields"), field.m))
+}
+
+// InsertField inserts the field after the field with the name after, at the start if after is empty.
+// The field is appended at the end if after does not exist.
+func (d *FeatureDef) InsertField(field *FieldDef, after string) {
+	fields := d.list("fields")
+	i := len(fields)
+	if after == "" {
+		i = 0
+	} else if j := slices.IndexFunc(fields, func(item any) bool {
+		f, ok := item.(*om.OrderedMap)
+		return ok && f.Map["name"] == after
+	}); j >= 0 {
+		i = j + 1
+	}
+	d.Set("fields", slices.Insert(slices.Clone(fields), i, any(field.m)))
+}
+
+// RemoveField removes the field from the "fields" list.
+// Returns *FieldNotFoundError if the field does not exist.
+func (d *FeatureDef) RemoveField(name string) error {
+	fields := d.list("fields")
+	newFields := slices.DeleteFunc(slices.Clone(fields), func(item any) bool {
+		field, ok := item.(*om.OrderedMap)
+		return ok && field.Map["name"] == name
+	})
+	if len(newFields) == len(fields) {
+		return &FieldNotFoundError{Path: d.Path, Feature: d.Name(), Field: name}
+	}
+	d.Set("fields", newFields)
+	return nil
+}
+
+// RemoveFromGroups removes the field from all groups.
+// Returns names of groups the field was removed from.
+func (d *FeatureDef) RemoveFromGroups(name string) (groups []string) {
+	for _, group := range d.Groups() {
+		if group.RemoveField(name) {
+			groups = append(groups, group.Name())
+		}
+	}
+	return
+}
+
+// Groups returns the group definitions in definition order.
+// Entries of the "groups" list which are not objects are skipped.
+func (d *FeatureDef) Groups() (groups []*GroupDef) {
+	for _, item := range d.list("groups") {
+		if group, ok := item.(*om.OrderedMap); ok {
+			groups = append(groups, &GroupDef{Object: Object{m: group}})
+		}
+	}
+	return
+}
+
+// Group returns the group with the given name or nil if it does not exist
+func (d *FeatureDef) Group(name string) *GroupDef {
+	for _, group := range d.Groups() {
+		if group.Name() == name {
+			return group
+		}
+	}
+	return nil
+}
+
+// AddGroup appends the group at the end of the "groups" list
+func (d *FeatureDef) AddGroup(group *GroupDef) {
+	d.Set("groups", append(d.list("groups"), group.m))
+}
+
+// RemoveGroup removes the group from the "groups" list.
+// Returns false if the group does not exist.
+func (d *FeatureDef) RemoveGroup(name string) bool {
+	groups := d.list("groups")
+	newGroups := slices.DeleteFunc(slices.Clone(groups), func(item any) bool {
+		group, ok := item.(*om.OrderedMap)
+		return ok && group.Map["name"] == name
+	})
+	if len(newGroups) == len(groups) {
+		return false
+	}
+	d.Set("groups", newGroups)
+	return true
+}
+
+// NewFieldDef creates a field definition with the given name
+func NewFieldDef(name string) *FieldDef {
+	field := &FieldDef{Object: Object{m: om.NewOrderedMap()}}
+	field.Set("name", name)
+	return field
+}
+
+// Clone returns a deep copy of the field definition
+func (f *FieldDef) Clone() *FieldDef {
+	return &FieldDef{Object: Object{m: cloneValue(f.m).(*om.OrderedMap)}}
+}
+
+// Name returns the internal name of the field
+func (f *FieldDef) Name() string {
+	return f.String("name")
+}
+
+// ExternalName returns the display name of the field
+func (f *FieldDef) ExternalName() string {
+	return f.String("external_name")
+}
+
+// Type returns the type string of the field, e.g. "string(100)"
+func (f *FieldDef) Type() string {
+	return f.String("type")
+}
+
+// Value returns the value expression of the field, e.g. "method(name)"
+func (f *FieldDef) Value() string {
+	return f.String("value")
+}
+
+// Unit returns the unit of the field
+func (f *FieldDef) Unit() string {
+	return f.String("unit")
+}
+
+// MethodName returns the name of the method called by a calculated field
+// with value "method(name)". The second result is false for other fields.
+func (f *FieldDef) MethodName() (string, bool) {
+	match := methodValuePattern.FindStringSubmatch(f.Value())
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// MarshalJSON implements json.Marshaler
+func (f *FieldDef) MarshalJSON() ([]byte, error) {
+	return f.m.MarshalJSON()
+}
+
+// NewGroupDef creates a visible, collapsed group with the given fields
+func NewGroupDef(name string, fields []string) *GroupDef {
+	group := &GroupDef{Object: Object{m: om.NewOrderedMap()}}
+	group.Set("name", name)
+	group.Set("visible", true)
+	group.Set("expanded", false)
+	group.SetFields(fields)
+	return group
+}
+
+// Clone returns a deep copy of the group definition
+func (g *GroupDef) Clone() *GroupDef {
+	return &GroupDef{Object: Object{m: cloneValue(g.m).(*om.OrderedMap)}}
+}
+
+// Name returns the name of the group
+func (g *GroupDef) Name() string {
+	return g.String("name")
+}
+
+// Fields returns the names of fields in the group. Non string entries are skipped.
+func (g *GroupDef) Fields() (fields []string) {
+	for _, item := range g.list("fields") {
+		if name, ok := item.(string); ok {
+			fields = append(fields, name)
+		}
+	}
+	return
+}
+
+// SetFields replaces the list of fields in the group
+func (g *GroupDef) SetFields(fields []string) {
+	list := make([]any, len(fields))
+	for i, name := range fields {
+		list[i] = name
+	}
+	g.Set("fields", list)
+}
+
+// InsertField inserts the field after the field after, at the start if after is empty.
+// The field is appended at the end if after is not in the group.
+// Returns false if the group already has the field.
+func (g *GroupDef) InsertField(name string, after string) bool {
+	fields := g.list("fields")
+	if slices.Contains(fields, any(name)) {
+		return false
+	}
+	i := len(fields)
+	if after == "" {
+		i = 0
+	} else if j := slices.Index(fields, any(after)); j >= 0 {
+		i = j + 1
+	}
+	g.Set("fields", slices.Insert(slices.Clone(fields), i, any(name)))
+	return true
+}
+
+// RemoveField removes the field from the group. Returns false if the group does not have the field.
+func (g *GroupDef) RemoveField(name string) bool {
+	fields := g.list("fields")
+	newFields := slices.DeleteFunc(slices.Clone(fields), func(item any) bool {
+		return item == name
+	})
+	if len(newFields) == len(fields) {
+		return false
+	}
+	g.Set("fields", newFields)
+	return true
+}
+
+// MarshalJSON implements json.Marshaler
+func (g *GroupDef) MarshalJSON() ([]byte, error) {
+	return g.m.MarshalJSON()
+}
+
+// cloneValue returns a deep copy of a value parsed into an ordered map
+func cloneValue(value any) any {
+	switch v := value.(type) {
+	case *om.OrderedMap:
+		clone := om.NewOrderedMap()
+		for _, key := range v.Keys {
+			clone.Set(key, cloneValue(v.Map[key]))
+		}
+		return clone
+	case []any:
+		clone := make([]any, len(v))
+		for i, item := range v {
+			clone[i] = cloneValue(item)
+		}
+		return clone
+	case []string:
+		return slices.Clone(v)
+	}
+	return value
+}
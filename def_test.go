@@ -0,0 +1,87 @@
+package superobject
+
+import (
+	"bufio"
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func mustParseDef(t *testing.T, data string) *FeatureDef {
+	t.Helper()
+	def, err := ParseFeatureDef([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return def
+}
+
+// roundTripDef is in canonical style and has unknown keys at all levels, keys out of alphabetical
+// order, numbers which change when read as float and characters escaped by default by encoding/json
+const roundTripDef = `{
+    "name": "eo_cable",
+    "zz_custom": {
+        "b": 1.50,
+        "a": [
+            1e3,
+            null,
+            true
+        ]
+    },
+    "external_name": "Kabel & Leiding <HS>",
+    "fields": [
+        {
+            "name": "voltage",
+            "external_name": "Spanning",
+            "type": "double",
+            "x_unknown": "é"
+        }
+    ],
+    "groups": [
+        {
+            "name": "Algemeen",
+            "fields": [
+                "voltage"
+            ],
+            "expanded": false
+        }
+    ],
+    "min_select": 0
+}
+`
+
+func writeDef(t *testing.T, def *FeatureDef) string {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := bufio.NewWriter(&buf)
+	if err := WriteFeatureDef(writer, def.Raw()); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestRoundTrip(t *testing.T) {
+	def := mustParseDef(t, roundTripDef)
+	if got := writeDef(t, def); got != roundTripDef {
+		t.Errorf("written definition differs from parsed one:\n%s", got)
+	}
+	if got := writeDef(t, def.Clone()); got != roundTripDef {
+		t.Errorf("written clone differs from parsed definition:\n%s", got)
+	}
+}
+
+func TestSetKeepsKeyOrder(t *testing.T) {
+	def := mustParseDef(t, `{"name": "a", "x": 1, "fields": [{"name": "f", "type": "integer", "y": 2}]}`)
+	def.Set("x", 3)
+	def.Set("z", 4)
+	def.Field("f").Set("type", "double")
+	if got, want := def.Keys(), []string{"name", "x", "fields", "z"}; !slices.Equal(got, want) {
+		t.Errorf("keys = %v, want %v", got, want)
+	}
+	if got, want := def.Field("f").Keys(), []string{"name", "type", "y"}; !slices.Equal(got, want) {
+		t.Errorf("field keys = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,120 @@
+package superobject
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Severity of a diagnostic
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "Warning"
+	}
+	return "Error"
+}
+
+// Diagnostic is a single problem collected while processing definitions
+type Diagnostic struct {
+	Severity Severity
+	Err      error
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %v", d.Severity, d.Err)
+}
+
+// Diagnostics collects errors and warnings, so commands can process all files
+// and report every problem at the end instead of stopping on the first one.
+// The zero value is ready to use and safe for concurrent use.
+type Diagnostics struct {
+	mutex sync.Mutex
+	items []Diagnostic
+}
+
+// Error records the error. Returns true if err was not nil, so it can be used as
+//
+//	if diag.Error(err) {
+//		continue
+//	}
+func (d *Diagnostics) Error(err error) bool {
+	return d.add(SeverityError, err)
+}
+
+// Errorf records a formatted error
+func (d *Diagnostics) Errorf(format string, args ...any) {
+	d.add(SeverityError, fmt.Errorf(format, args...))
+}
+
+// Warning records the warning. Returns true if err was not nil.
+func (d *Diagnostics) Warning(err error) bool {
+	return d.add(SeverityWarning, err)
+}
+
+// Warningf records a formatted warning
+func (d *Diagnostics) Warningf(format string, args ...any) {
+	d.add(SeverityWarning, fmt.Errorf(format, args...))
+}
+
+func (d *Diagnostics) add(severity Severity, err error) bool {
+	if err == nil {
+		return false
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.items = append(d.items, Diagnostic{Severity: severity, Err: err})
+	return true
+}
+
+// Items returns all collected diagnostics in the order they were recorded
+func (d *Diagnostics) Items() []Diagnostic {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]Diagnostic(nil), d.items...)
+}
+
+// Count returns the number of collected diagnostics with given severity
+func (d *Diagnostics) Count(severity Severity) (count int) {
+	for _, item := range d.Items() {
+		if item.Severity == severity {
+			count++
+		}
+	}
+	return
+}
+
+// HasErrors returns true if at least one error was recorded
+func (d *Diagnostics) HasErrors() bool {
+	return d.Count(SeverityError) > 0
+}
+
+// ExitCode returns the process exit code, 1 if any error was recorded, otherwise 0
+func (d *Diagnostics) ExitCode() int {
+	if d.HasErrors() {
+		return 1
+	}
+	return 0
+}
+
+// Report writes all diagnostics and a summary line to the writer and returns the exit code.
+// Nothing is written when there are no diagnostics.
+//
+//	os.Exit(diag.Report(os.Stderr))
+func (d *Diagnostics) Report(w io.Writer) int {
+	items := d.Items()
+	if len(items) == 0 {
+		return 0
+	}
+	for _, item := range items {
+		fmt.Fprintln(w, item)
+	}
+	fmt.Fprintf(w, "%d error(s), %d warning(s)\n", d.Count(SeverityError), d.Count(SeverityWarning))
+	return d.ExitCode()
+}
@@ -0,0 +1,103 @@
+package superobject
+
+import (
+	"slices"
+)
+
+// DriftKind is the kind of difference between a super object and its component
+type DriftKind string
+
+const (
+	// DriftMissingComponent - component definition of calculated fields does not exist
+	DriftMissingComponent DriftKind = "missing_component"
+	// DriftMissingSource - source field of the calculated field does not exist in the component
+	DriftMissingSource DriftKind = "missing_source"
+	// DriftType - type of the component field changed
+	DriftType DriftKind = "type"
+	// DriftExternalName - external name of the component field changed
+	DriftExternalName DriftKind = "external_name"
+	// DriftUnit - unit of the component field changed
+	DriftUnit DriftKind = "unit"
+	// DriftNewField - component field is not in the super object yet
+	DriftNewField DriftKind = "new_field"
+)
+
+// Drift is one difference between a calculated field and its component field
+type Drift struct {
+	Kind      DriftKind `json:"kind"`
+	Component string    `json:"component"`
+	// Field is the calculated field, empty for new fields
+	Field string `json:"field,omitempty"`
+	// Source is the field of the component
+	Source string `json:"source,omitempty"`
+	// Old is the value in the super object, New is the value in the component
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+	// TypeChange is the effect of the type change for DriftType, TypeChangeSafe for other kinds
+	TypeChange TypeChange `json:"type_change"`
+}
+
+// DriftReport lists differences between a super object and current definitions of its components
+type DriftReport struct {
+	Feature    string   `json:"feature"`
+	Path       string   `json:"path"`
+	Components []string `json:"components"`
+	Drifts     []Drift  `json:"drifts"`
+}
+
+// Components returns names of components of the super object, found from names of calculated fields
+func (c *Composer) Components(def *FeatureDef) (components []string) {
+	for _, field := range def.Fields() {
+		if featureName, _, ok := c.Naming.ParseCalcFieldName(field.Name()); ok && !slices.Contains(components, featureName) {
+			components = append(components, featureName)
+		}
+	}
+	return
+}
+
+// Drift compares calculated fields of the component in the super object with the current component definition.
+// Component fields selected by FieldRules which are not in the super object are reported as DriftNewField.
+// component nil means the component definition does not exist.
+func (c *Composer) Drift(def *FeatureDef, componentName string, component *FeatureDef) (drifts []Drift) {
+	if component == nil {
+		return []Drift{{Kind: DriftMissingComponent, Component: componentName}}
+	}
+	sources := map[string]bool{}
+	for _, field := range def.Fields() {
+		featureName, fieldName, ok := c.Naming.ParseCalcFieldName(field.Name())
+		if !ok || featureName != componentName {
+			continue
+		}
+		sources[fieldName] = true
+		drift := Drift{Component: componentName, Field: field.Name(), Source: fieldName}
+		source := component.Field(fieldName)
+		if source == nil {
+			drift.Kind = DriftMissingSource
+			drifts = append(drifts, drift)
+			continue
+		}
+		if field.Type() != source.Type() {
+			drift := drift
+			drift.Kind, drift.Old, drift.New = DriftType, field.Type(), source.Type()
+			drift.TypeChange = CompareTypeStrings(field.Type(), source.Type())
+			drifts = append(drifts, drift)
+		}
+		if field.ExternalName() != source.ExternalName() {
+			drift := drift
+			drift.Kind, drift.Old, drift.New = DriftExternalName, field.ExternalName(), source.ExternalName()
+			drifts = append(drifts, drift)
+		}
+		if field.Unit() != source.Unit() {
+			drift := drift
+			drift.Kind, drift.Old, drift.New = DriftUnit, field.Unit(), source.Unit()
+			drifts = append(drifts, drift)
+		}
+	}
+	rules := c.FieldRules()
+	for _, field := range component.Fields() {
+		if included, _ := rules.Match(field); included && !sources[field.Name()] {
+			drifts = append(drifts, Drift{Kind: DriftNewField, Component: componentName, Source: field.Name(), New: field.Type()})
+		}
+	}
+	return
+}
@@ -0,0 +1,39 @@
+package superobject
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestDrift(t *testing.T) {
+	def := mustParseDef(t, `{"name": "so_cable", "fields": [
+		{"name": "id", "type": "integer"},
+		{"name": "calc__eo_cable__voltage", "type": "double", "external_name": "Voltage", "unit": "V"},
+		{"name": "calc__eo_cable__label", "type": "string(10)", "external_name": "Label"},
+		{"name": "calc__eo_cable__owner", "type": "string(10)"},
+		{"name": "calc__eo_duct__size", "type": "integer"}]}`)
+	composer := NewComposer()
+	if got, want := composer.Components(def), []string{"eo_cable", "eo_duct"}; !slices.Equal(got, want) {
+		t.Errorf("components = %v, want %v", got, want)
+	}
+	component := mustParseDef(t, `{"name": "eo_cable", "fields": [
+		{"name": "voltage", "type": "double", "external_name": "Spanning", "unit": "kV"},
+		{"name": "label", "type": "string(5)", "external_name": "Label"},
+		{"name": "status", "type": "string(10)"},
+		{"name": "myw_internal", "type": "string(10)"}]}`)
+	want := []Drift{
+		{Kind: DriftExternalName, Component: "eo_cable", Field: "calc__eo_cable__voltage", Source: "voltage", Old: "Voltage", New: "Spanning"},
+		{Kind: DriftUnit, Component: "eo_cable", Field: "calc__eo_cable__voltage", Source: "voltage", Old: "V", New: "kV"},
+		{Kind: DriftType, Component: "eo_cable", Field: "calc__eo_cable__label", Source: "label", Old: "string(10)", New: "string(5)", TypeChange: TypeChangeLossy},
+		{Kind: DriftMissingSource, Component: "eo_cable", Field: "calc__eo_cable__owner", Source: "owner"},
+		{Kind: DriftNewField, Component: "eo_cable", Source: "status", New: "string(10)"},
+	}
+	if got := composer.Drift(def, "eo_cable", component); !reflect.DeepEqual(got, want) {
+		t.Errorf("drifts =\n%+v\nwant\n%+v", got, want)
+	}
+	want = []Drift{{Kind: DriftMissingComponent, Component: "eo_duct"}}
+	if got := composer.Drift(def, "eo_duct", nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("missing component drifts = %+v, want %+v", got, want)
+	}
+}
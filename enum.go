@@ -0,0 +1,152 @@
+package superobject
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// EnumDef is a typed view of a myWorld enumerator definition (.enum file)
+type EnumDef struct {
+	Object
+	// Path is the file the enumerator was read from
+	Path string
+	// Hash of the file content when it was read, see FileHash
+	Hash string
+	data []byte
+}
+
+// EnumRef is a reference to an enumerator from a calculated field
+type EnumRef struct {
+	Enum      string `json:"enum"`
+	Field     string `json:"field"`
+	Component string `json:"component"`
+}
+
+// EnumReport describes enumerators copied by CopyEnums
+type EnumReport struct {
+	// Copied enumerators, written to the output dir
+	Copied []string `json:"copied"`
+	// Unchanged enumerators, already in the output dir with the same values
+	Unchanged []string `json:"unchanged"`
+	// Missing enumerators, not found in any source dir
+	Missing []EnumRef `json:"missing,omitempty"`
+	// Conflicts are enumerators in the output dir with values different from the component enumerator
+	Conflicts []string `json:"conflicts,omitempty"`
+}
+
+// ReadEnumFile reads the enumerator definition from a file.
+// Returns *MalformedDefError if the file is not a valid JSON object.
+func ReadEnumFile(path string) (enum *EnumDef, err error) {
+	var (
+		data []byte
+		def  *FeatureDef
+	)
+	if data, err = os.ReadFile(path); err != nil {
+		err = fmt.Errorf("failed to read enumerator: %w", err)
+		return
+	}
+	if def, err = parseFeatureDef(path, data); err != nil {
+		return
+	}
+	enum = &EnumDef{Object: def.Object, Path: path, Hash: FileHash(data), data: data}
+	return
+}
+
+// Name returns name of the enumerator
+func (e *EnumDef) Name() string {
+	return e.String("name")
+}
+
+// Values returns values of the enumerator
+func (e *EnumDef) Values() []any {
+	return e.list("values")
+}
+
+// SameValues returns true if both enumerators have identical values, in the same order
+func (e *EnumDef) SameValues(other *EnumDef) bool {
+	return reflect.DeepEqual(e.Values(), other.Values())
+}
+
+// EnumFileName returns name of the file of the enumerator
+func EnumFileName(name string) string {
+	return name + ".enum"
+}
+
+// Enums returns enumerators referenced by calculated fields of the definition, in the order of fields
+func (c *Composer) Enums(def *FeatureDef) (refs []EnumRef) {
+	for _, field := range def.Fields() {
+		featureName, _, ok := c.Naming.ParseCalcFieldName(field.Name())
+		if !ok {
+			continue
+		}
+		if enum := field.String("enum"); enum != "" {
+			refs = append(refs, EnumRef{Enum: enum, Field: field.Name(), Component: featureName})
+		}
+	}
+	return
+}
+
+// CopyEnums copies enumerators referenced by calculated fields of the definition from the first of srcDirs
+// which has them to destDir, so the composed super object has the same enumerators as its components.
+// Missing enumerators are recorded in diag as *EnumNotFoundError. An enumerator which is already in destDir
+// with different values is not overwritten, it is recorded in diag as *EnumConflictError.
+func (c *Composer) CopyEnums(def *FeatureDef, srcDirs []string, destDir string, opts WriteOptions, diag *Diagnostics) (report *EnumReport) {
+	report = &EnumReport{}
+	done := map[string]bool{}
+	for _, ref := range c.Enums(def) {
+		if done[ref.Enum] {
+			continue
+		}
+		done[ref.Enum] = true
+		enum, err := findEnum(ref.Enum, srcDirs)
+		if errors.Is(err, fs.ErrNotExist) {
+			report.Missing = append(report.Missing, ref)
+			diag.Error(&EnumNotFoundError{Path: def.Path, Enum: ref.Enum, Field: ref.Field})
+			continue
+		}
+		if diag.Error(err) {
+			continue
+		}
+		destPath := filepath.Join(destDir, EnumFileName(ref.Enum))
+		if sameFile(destPath, enum.Path) {
+			report.Unchanged = append(report.Unchanged, ref.Enum)
+			continue
+		}
+		dest, err := ReadEnumFile(destPath)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case diag.Error(err):
+			continue
+		case !dest.SameValues(enum):
+			report.Conflicts = append(report.Conflicts, ref.Enum)
+			diag.Error(&EnumConflictError{Path: destPath, Source: enum.Path, Enum: ref.Enum})
+			continue
+		case bytes.Equal(dest.data, enum.data):
+			report.Unchanged = append(report.Unchanged, ref.Enum)
+			continue
+		}
+		if diag.Error(WriteFileAtomic(destPath, enum.data, opts)) {
+			continue
+		}
+		report.Copied = append(report.Copied, ref.Enum)
+	}
+	return
+}
+
+// findEnum reads the enumerator from the first dir which has it
+func findEnum(name string, dirs []string) (enum *EnumDef, err error) {
+	err = fmt.Errorf("enumerator %s: %w", name, fs.ErrNotExist)
+	for _, dir := range dirs {
+		path := filepath.Join(dir, EnumFileName(name))
+		if _, statErr := os.Stat(path); statErr != nil {
+			continue
+		}
+		return ReadEnumFile(path)
+	}
+	return
+}
@@ -0,0 +1,83 @@
+package superobject
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCopyEnums(t *testing.T) {
+	const (
+		voltages = `{"name": "voltages", "values": [{"value": "10kV"}, {"value": "400V"}]}`
+		other    = `{"name": "voltages", "values": [{"value": "10kV"}]}`
+	)
+	def := mustParseDef(t, `{"name": "so_cable", "fields": [
+		{"name": "calc__eo_cable__voltage", "type": "string(10)", "enum": "voltages"},
+		{"name": "calc__eo_duct__voltage", "type": "string(10)", "enum": "voltages"},
+		{"name": "calc__eo_cable__status", "type": "string(10)", "enum": "statuses"},
+		{"name": "status", "type": "string(10)", "enum": "so_statuses"}
+	]}`)
+	tests := []struct {
+		name   string
+		dest   map[string]string
+		report EnumReport
+		errs   []error
+	}{
+		{
+			name:   "copied",
+			report: EnumReport{Copied: []string{"voltages"}, Missing: []EnumRef{{Enum: "statuses", Field: "calc__eo_cable__status", Component: "eo_cable"}}},
+			errs:   []error{ErrEnumNotFound},
+		},
+		{
+			name:   "unchanged",
+			dest:   map[string]string{"voltages.enum": voltages},
+			report: EnumReport{Unchanged: []string{"voltages"}, Missing: []EnumRef{{Enum: "statuses", Field: "calc__eo_cable__status", Component: "eo_cable"}}},
+			errs:   []error{ErrEnumNotFound},
+		},
+		{
+			name:   "conflict",
+			dest:   map[string]string{"voltages.enum": other},
+			report: EnumReport{Conflicts: []string{"voltages"}, Missing: []EnumRef{{Enum: "statuses", Field: "calc__eo_cable__status", Component: "eo_cable"}}},
+			errs:   []error{ErrEnumConflict, ErrEnumNotFound},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcDir, destDir := t.TempDir(), t.TempDir()
+			writeFiles(t, srcDir, map[string]string{"voltages.enum": voltages})
+			writeFiles(t, destDir, tt.dest)
+			var diag Diagnostics
+			report := NewComposer().CopyEnums(def, []string{srcDir}, destDir, WriteOptions{}, &diag)
+			if !reflect.DeepEqual(*report, tt.report) {
+				t.Errorf("report = %+v, want %+v", *report, tt.report)
+			}
+			items := diag.Items()
+			if len(items) != len(tt.errs) {
+				t.Fatalf("got diagnostics %v, want %v", items, tt.errs)
+			}
+			for i, item := range items {
+				if !errors.Is(item.Err, tt.errs[i]) {
+					t.Errorf("diagnostic %v, want %v", item.Err, tt.errs[i])
+				}
+			}
+			want := voltages
+			if tt.dest != nil {
+				want = tt.dest["voltages.enum"]
+			}
+			if got := readFile(t, filepath.Join(destDir, "voltages.enum")); got != want {
+				t.Errorf("voltages.enum = %s, want %s", got, want)
+			}
+		})
+	}
+}
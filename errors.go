@@ -1,10 +1,167 @@
 package superobject
 
-import "fmt"
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
 
+var (
+	// ErrFieldNotFound is returned when a field does not exist in the feature definition
+	ErrFieldNotFound = errors.New("field not found")
+	// ErrMalformedDef is returned when a feature definition is not a valid JSON object
+	ErrMalformedDef = errors.New("malformed feature definition")
+	// ErrUnexpectedType is returned when a value in a feature definition has a wrong JSON type
+	ErrUnexpectedType = errors.New("unexpected type")
+	// ErrUnknownType is returned when a field type has a kind which is not known to this package
+	ErrUnknownType = errors.New("unknown type")
+	// ErrComponentNotFound is returned when a super object has no calculated fields of a component
+	ErrComponentNotFound = errors.New("component not found")
+	// ErrEnumNotFound is returned when an enumerator used by a field does not exist
+	ErrEnumNotFound = errors.New("enumerator not found")
+	// ErrEnumConflict is returned when an enumerator has different values in component and super object
+	ErrEnumConflict = errors.New("enumerator values differ")
+	// ErrBlockingReference is returned when a field can not be removed, because a reference to it can not be rewritten safely
+	ErrBlockingReference = errors.New("reference can not be removed safely")
+)
+
+// FieldNotFoundError reports a field missing in the feature definition.
+// errors.Is(err, ErrFieldNotFound) is true for this error.
+type FieldNotFoundError struct {
+	Path    string
+	Feature string
+	Field   string
+}
+
+func (e *FieldNotFoundError) Error() string {
+	return withPath(e.Path, fmt.Sprintf("%s: field %s: %v", e.Feature, e.Field, ErrFieldNotFound))
+}
+
+func (e *FieldNotFoundError) Unwrap() error {
+	return ErrFieldNotFound
+}
+
+// MalformedDefError reports a feature definition file which can not be parsed.
+// errors.Is(err, ErrMalformedDef) is true for this error.
+type MalformedDefError struct {
+	Path string
+	// Offset is the JSON byte offset of the problem, -1 if unknown
+	Offset int64
+	// Line and Column of the problem, starting from 1. 0 if unknown
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *MalformedDefError) Error() string {
+	path := e.Path
+	if e.Line > 0 {
+		path = fmt.Sprintf("%s:%d:%d", path, e.Line, e.Column)
+	}
+	return withPath(path, fmt.Sprintf("%v: %v", ErrMalformedDef, e.Err))
+}
+
+func (e *MalformedDefError) Unwrap() []error {
+	return []error{ErrMalformedDef, e.Err}
+}
+
+// UnexpectedTypeError reports a value of wrong JSON type, e.g. a field name which is not a string.
+// errors.Is(err, ErrUnexpectedType) is true for this error.
+type UnexpectedTypeError struct {
+	Path string
+	// Key is the location of the value in the definition, e.g. "fields[3].name"
+	Key      string
+	Expected string
+	Got      any
+}
+
+func (e *UnexpectedTypeError) Error() string {
+	return withPath(e.Path, fmt.Sprintf("%s: %v: expected %s, got %s", e.Key, ErrUnexpectedType, e.Expected, jsonTypeName(e.Got)))
+}
+
+func (e *UnexpectedTypeError) Unwrap() error {
+	return ErrUnexpectedType
+}
+
+// EnumNotFoundError reports an enumerator used by a field which does not exist.
+// errors.Is(err, ErrEnumNotFound) is true for this error.
+type EnumNotFoundError struct {
+	Path  string
+	Enum  string
+	Field string
+}
+
+func (e *EnumNotFoundError) Error() string {
+	return withPath(e.Path, fmt.Sprintf("field %s: enumerator %s: %v", e.Field, e.Enum, ErrEnumNotFound))
+}
+
+func (e *EnumNotFoundError) Unwrap() error {
+	return ErrEnumNotFound
+}
+
+// EnumConflictError reports an enumerator file with values different from the component enumerator.
+// errors.Is(err, ErrEnumConflict) is true for this error.
+type EnumConflictError struct {
+	Path string
+	// Source is the component enumerator file
+	Source string
+	Enum   string
+}
+
+func (e *EnumConflictError) Error() string {
+	return withPath(e.Path, fmt.Sprintf("enumerator %s: %v from %s", e.Enum, ErrEnumConflict, e.Source))
+}
+
+func (e *EnumConflictError) Unwrap() error {
+	return ErrEnumConflict
+}
+
+func withPath(path string, msg string) string {
+	if path == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s: %s", path, msg)
+}
+
+// BlockingReferenceError reports references which block removal of a field, see FeatureDef.RemoveFieldCascade.
+// errors.Is(err, ErrBlockingReference) is true for this error.
+type BlockingReferenceError struct {
+	Path       string
+	Feature    string
+	Field      string
+	References []Reference
+}
+
+func (e *BlockingReferenceError) Error() string {
+	refs := make([]string, len(e.References))
+	for i, ref := range e.References {
+		refs[i] = ref.String()
+	}
+	return withPath(e.Path, fmt.Sprintf("%s: field %s: %v: %s", e.Feature, e.Field, ErrBlockingReference, strings.Join(refs, "; ")))
+}
+
+func (e *BlockingReferenceError) Unwrap() error {
+	return ErrBlockingReference
+}
+
+// newMalformedDefError creates error with line and column calculated from the offset
+func newMalformedDefError(path string, data []byte, offset int64, err error) *MalformedDefError {
+	e := &MalformedDefError{Path: path, Offset: offset, Err: err}
+	if offset >= 0 && offset <= int64(len(data)) {
+		before := data[:offset]
+		e.Line = bytes.Count(before, []byte("\n")) + 1
+		e.Column = int(offset) - (bytes.LastIndexByte(before, '\n') + 1) + 1
+	}
+	return e
+}
+
+// HandleErr prints the error and panics.
+//
+// Deprecated: collect errors with Diagnostics, so all problems are reported.
 func HandleErr(err error) {
 	if err != nil {
 		fmt.Println("Error:", err)
 		panic(err)
 	}
-}
\ No newline at end of file
+}
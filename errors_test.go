@@ -0,0 +1,38 @@
+package superobject
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMalformedDefError(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		line   int
+		column int
+		cause  error
+	}{
+		{name: "syntax error", data: "{\n  \"name\": \"a\",\n  \"fields\": [,]\n}", line: 3, column: 14},
+		{name: "syntax error on first line", data: `{"name" "a"}`, line: 1, column: 9},
+		{name: "not an object", data: `[1]`, line: 1, column: 1, cause: ErrUnexpectedType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFeatureDef([]byte(tt.data))
+			var malformed *MalformedDefError
+			if !errors.As(err, &malformed) {
+				t.Fatalf("got %v, want *MalformedDefError", err)
+			}
+			if !errors.Is(err, ErrMalformedDef) {
+				t.Errorf("errors.Is(%v, ErrMalformedDef) is false", err)
+			}
+			if tt.cause != nil && !errors.Is(err, tt.cause) {
+				t.Errorf("errors.Is(%v, %v) is false", err, tt.cause)
+			}
+			if malformed.Line != tt.line || malformed.Column != tt.column {
+				t.Errorf("got line %d column %d, want line %d column %d", malformed.Line, malformed.Column, tt.line, tt.column)
+			}
+		})
+	}
+}
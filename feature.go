@@ -49,14 +49,10 @@ func init() {
 // Return true if field already exists in the feature definition
 // featureDef: the feature definition to add the field to
 // fieldName: the name of the field to add
+//
+// Deprecated: use FeatureDef.HasField
 func IsFieldExists(featureDef *om.OrderedMap, fieldName string) bool {
-	fields := featureDef.Map["fields"].([]any)
-	for _, field := range fields {
-		if field.(*om.OrderedMap).Map["name"] == fieldName {
-			return true
-		}
-	}
-	return false
+	return WrapFeatureDef(featureDef).HasField(fieldName)
 }
 
 // AddField adds a new field to the feature definition
@@ -65,17 +61,14 @@ func IsFieldExists(featureDef *om.OrderedMap, fieldName string) bool {
 // externalName: the external name of the field to add
 // fieldType: the type of the field to add
 func AddField(featureDef *om.OrderedMap, fieldName string, externalName string, fieldType string, unit string) {
-	field := om.NewOrderedMap()
-	field.Set("name", fieldName)
+	field := NewFieldDef(fieldName)
 	field.Set("external_name", externalName)
 	field.Set("type", fieldType)
 	field.Set("value", fmt.Sprintf("method(%s)", fieldName))
 	if unit != "" {
 		field.Set("unit", unit)
 	}
-	fields := featureDef.Map["fields"].([]any)
-	fields = append(fields, field)
-	featureDef.Set("fields", fields)
+	WrapFeatureDef(featureDef).AddField(field)
 }
 
 // UpdateField updates an existing field in the feature definition
@@ -83,34 +76,29 @@ func AddField(featureDef *om.OrderedMap, fieldName string, externalName string,
 // fieldName: the name of the field to add
 // externalName: the external name of the field to add
 // fieldType: the type of the field to add
-func UpdateField(featureDef *om.OrderedMap, fieldName string, externalName string, fieldType string, unit string) {
-	fields := featureDef.Map["fields"].([]any)
-	for i, iField := range fields {
-		field := iField.(*om.OrderedMap)
-		if field.Map["name"] == fieldName {
-			field.Set("external_name", externalName)
-			field.Set("type", fieldType)
-			field.Set("value", fmt.Sprintf("method(%s)", fieldName))
-			if unit != "" {
-				field.Set("unit", unit)
-			}
-			fields[i] = field
-		}
+// Returns the effect of the type change on values already stored in the field.
+func UpdateField(featureDef *om.OrderedMap, fieldName string, externalName string, fieldType string, unit string) (change TypeChange) {
+	field := WrapFeatureDef(featureDef).Field(fieldName)
+	if field == nil {
+		return
+	}
+	change = CompareTypeStrings(field.Type(), fieldType)
+	field.Set("external_name", externalName)
+	field.Set("type", fieldType)
+	field.Set("value", fmt.Sprintf("method(%s)", fieldName))
+	if unit != "" {
+		field.Set("unit", unit)
 	}
-	featureDef.Set("fields", fields)
+	return
 }
 
 // Check if group already exists in the feature definition
 // featureDef: the feature definition to check
 // groupName: the name of the group to check
+//
+// Deprecated: use FeatureDef.Group
 func IsGroupExists(featureDef *om.OrderedMap, groupName string) bool {
-	groups := featureDef.Map["groups"].([]any)
-	for _, iGroup := range groups {
-		if iGroup.(*om.OrderedMap).Map["name"] == groupName {
-			return true
-		}
-	}
-	return false
+	return WrapFeatureDef(featureDef).Group(groupName) != nil
 }
 
 // AddGroup adds a new group to the feature definition
@@ -118,64 +106,119 @@ func IsGroupExists(featureDef *om.OrderedMap, groupName string) bool {
 // groupName: the name of the group to add
 // fields: list of fields to add to group
 func AddGroup(featureDef *om.OrderedMap, groupName string, fields []string) {
-	groups := featureDef.Map["groups"].([]any)
-	group := om.NewOrderedMap()
-	group.Set("name", groupName)
-	group.Set("visible", true)
-	group.Set("expanded", false)
-	group.Set("fields", fields)
-	groups = append(groups, group)
-	featureDef.Set("groups", groups)
+	WrapFeatureDef(featureDef).AddGroup(NewGroupDef(groupName, fields))
 }
 
-// UpdateGroup
+// UpdateGroup replaces the list of fields of an existing group
 func UpdateGroup(featureDef *om.OrderedMap, groupName string, fields []string) {
-	groups := featureDef.Map["groups"].([]any)
-	for i, iGroup := range groups {
-		group := iGroup.(*om.OrderedMap)
-		if group.Map["name"] == groupName {
-			group.Set("fields", fields)
-			groups[i] = group
-			break
-		}
+	if group := WrapFeatureDef(featureDef).Group(groupName); group != nil {
+		group.SetFields(fields)
 	}
-	featureDef.Set("groups", groups)
 }
 
 // Get list of fields from feature definition. Exclude fields with prefix "myw_"
 // and fields with type "reference_set", "reference", "linestring", "point", "polygon"
+//
+// Deprecated: use ListFields
 func GetFields(featureDef *om.OrderedMap, excluded []string) (fields []Field) {
+	return ListFields(WrapFeatureDef(featureDef), excluded)
+}
+
+// ListFields returns list of fields from feature definition. Fields with prefix "myw_"
+// and fields with type kind from excluded list are skipped, so "reference" excludes
+// "reference(eo_cable)" too. When excluded is nil DefaultExcludedFields are used.
+func ListFields(def *FeatureDef, excluded []string) (fields []Field) {
 	if excluded == nil {
 		excluded = DefaultExcludedFields
 	}
-	featureName := featureDef.Map["name"].(string)
-	fieldsDefs := featureDef.Map["fields"].([]any)
-	for _, fieldDef := range fieldsDefs {
-		field := fieldDef.(*om.OrderedMap)
-		fieldName := field.Map["name"].(string)
+	featureName := def.Name()
+	for _, field := range def.Fields() {
+		fieldName := field.Name()
 		if strings.HasPrefix(fieldName, "myw_") {
 			continue
 		}
-		externalName := field.Map["external_name"].(string)
-		fieldType := field.Map["type"].(string)
+		fieldType := field.Type()
 		if slices.Contains(excluded, fieldType) {
 			continue
 		}
-		unitValue := ""
-		if unit := field.Map["unit"]; unit != nil {
-			unitValue = unit.(string)
+		if t, err := ParseFieldType(fieldType); err == nil && slices.Contains(excluded, t.Kind) {
+			continue
 		}
-		fields = append(fields, Field{
-			FeatureName:  featureName,
-			Name:         fieldName,
-			ExternalName: externalName,
-			Type:         fieldType,
-			Unit:         unitValue,
-		})
+		fields = append(fields, newField(featureName, field))
 	}
 	return fields
 }
 
+func newField(featureName string, field *FieldDef) Field {
+	return Field{
+		FeatureName:  featureName,
+		Name:         field.Name(),
+		ExternalName: field.ExternalName(),
+		Type:         field.Type(),
+		Unit:         field.Unit(),
+	}
+}
+
+// CopyField copies the field from the source definition to the target one. The field is inserted after
+// the nearest field preceding it in the source which exists in the target, so the source order is kept.
+// Without such field it is inserted before the nearest following one, or appended.
+// Returns *FieldNotFoundError if the source has no such field.
+func CopyField(target *FeatureDef, source *FeatureDef, name string) error {
+	field := source.Field(name)
+	if field == nil {
+		return &FieldNotFoundError{Path: source.Path, Feature: source.Name(), Field: name}
+	}
+	target.InsertField(field.Clone(), insertAfter(target.FieldNames(), source.FieldNames(), name))
+	return nil
+}
+
+// CopyFieldGroups adds the field to the target groups with the same names as source groups which contain it.
+// The field is placed after its preceding neighbour in the source group, like in CopyField. Groups missing
+// in the target are created as copies of the source groups with the field only.
+// Returns names of groups the field was added to and names of created groups.
+func CopyFieldGroups(target *FeatureDef, source *FeatureDef, name string) (groups []string, created []string) {
+	for _, sourceGroup := range source.Groups() {
+		sourceNames := sourceGroup.Fields()
+		if !slices.Contains(sourceNames, name) {
+			continue
+		}
+		group := target.Group(sourceGroup.Name())
+		if group == nil {
+			group = sourceGroup.Clone()
+			group.SetFields(nil)
+			target.AddGroup(group)
+			created = append(created, group.Name())
+		}
+		if group.InsertField(name, insertAfter(group.Fields(), sourceNames, name)) {
+			groups = append(groups, group.Name())
+		}
+	}
+	return
+}
+
+// insertAfter returns the name in targetNames after which the name should be inserted to keep the order of sourceNames,
+// empty to insert it at the start
+func insertAfter(targetNames []string, sourceNames []string, name string) string {
+	i := slices.Index(sourceNames, name)
+	for j := i - 1; j >= 0; j-- {
+		if slices.Contains(targetNames, sourceNames[j]) {
+			return sourceNames[j]
+		}
+	}
+	for _, following := range sourceNames[i+1:] {
+		if j := slices.Index(targetNames, following); j >= 0 {
+			if j == 0 {
+				return ""
+			}
+			return targetNames[j-1]
+		}
+	}
+	if len(targetNames) == 0 {
+		return ""
+	}
+	return targetNames[len(targetNames)-1]
+}
+
 // Reads the feature definition from a file
 func ReadFeatureDef(reader *bufio.Reader) (feature *om.OrderedMap, err error) {
 	var (
@@ -194,29 +237,28 @@ func ReadFeatureDef(reader *bufio.Reader) (feature *om.OrderedMap, err error) {
 	return
 }
 
-// Writes the feature definition to a file
-// The function replaces all occurrences of "\u0026" with "&" in the JSON output
+// Writes the feature definition to a file in canonical style, see Format.
+// The writer is flushed.
 func WriteFeatureDef(writer *bufio.Writer, feature *om.OrderedMap) (err error) {
-	var (
-		buf bytes.Buffer
-		e   *json.Encoder
-	)
-	if e = json.NewEncoder(&buf); e == nil {
+	var res []byte
+	if res, err = Format(WrapFeatureDef(feature)); err != nil {
 		return
 	}
-	e.SetIndent("", "  ")
-	e.SetEscapeHTML(false)
-	if err = e.Encode(feature); err != nil {
-		return
-	}
-	res := bytes.ReplaceAll(buf.Bytes(), []byte("\\u0026"), []byte("&"))
 	if _, err = writer.Write(res); err != nil {
 		err = fmt.Errorf("failed to write feature definition: %w", err)
 		return
 	}
+	if err = writer.Flush(); err != nil {
+		err = fmt.Errorf("failed to write feature definition: %w", err)
+	}
 	return
 }
 
+// WriteDef writes the feature definition in canonical style, see Format
+func WriteDef(w io.Writer, def *FeatureDef) (err error) {
+	return WriteFeatureDef(bufio.NewWriter(w), def.Raw())
+}
+
 // GetMethodBody generates the method body for a field in a feature definition
 func GetMethodBody(methodName string, featureName string, fieldName string) (body string) {
 	buff := bytes.NewBuffer([]byte{})
@@ -0,0 +1,118 @@
+package superobject
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// nameList splits space separated names
+func nameList(s string) []string {
+	return strings.Fields(s)
+}
+
+func TestInsertAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		source string
+		field  string
+		want   string
+	}{
+		{name: "preceding neighbour", target: "a c", source: "a b c", field: "b", want: "a"},
+		{name: "nearest existing preceding", target: "a x", source: "a y b", field: "b", want: "a"},
+		{name: "only following neighbour", target: "x c", source: "b c", field: "b", want: "x"},
+		{name: "following neighbour at start", target: "c x", source: "b c", field: "b", want: ""},
+		{name: "no neighbours", target: "x y", source: "a b", field: "b", want: "y"},
+		{name: "empty target", target: "", source: "a b", field: "b", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := insertAfter(nameList(tt.target), nameList(tt.source), tt.field); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopyField(t *testing.T) {
+	source := mustParseDef(t, `{"name": "f", "fields": [
+		{"name": "a", "type": "integer"}, {"name": "b", "type": "string(10)", "unit": "m"}, {"name": "c", "type": "integer"}]}`)
+	target := mustParseDef(t, `{"name": "f", "fields": [{"name": "c", "type": "integer"}, {"name": "x", "type": "integer"}]}`)
+	for _, name := range []string{"b", "a"} {
+		if err := CopyField(target, source, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := target.FieldNames(), nameList("a b c x"); !slices.Equal(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+	target.Field("b").Set("unit", "km")
+	if got := source.Field("b").Unit(); got != "m" {
+		t.Errorf("copied field shares data with the source, source unit = %s", got)
+	}
+	if err := CopyField(target, source, "missing"); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("got %v, want ErrFieldNotFound", err)
+	}
+}
+
+func TestFeatureInsertField(t *testing.T) {
+	def := mustParseDef(t, `{"name": "f", "fields": [{"name": "a", "type": "integer"}, {"name": "b", "type": "integer"}]}`)
+	def.InsertField(NewFieldDef("start"), "")
+	def.InsertField(NewFieldDef("after_a"), "a")
+	def.InsertField(NewFieldDef("end"), "missing")
+	if got, want := def.FieldNames(), nameList("start a after_a b end"); !slices.Equal(got, want) {
+		t.Errorf("fields = %v, want %v", got, want)
+	}
+}
+
+func TestGroupInsertField(t *testing.T) {
+	def := mustParseDef(t, `{"name": "f", "groups": [{"name": "G", "fields": ["a", "b"]}]}`)
+	group := def.Group("G")
+	for _, insert := range []struct{ name, after string }{{"start", ""}, {"after_a", "a"}, {"end", "missing"}} {
+		if !group.InsertField(insert.name, insert.after) {
+			t.Errorf("field %s was not inserted", insert.name)
+		}
+	}
+	if group.InsertField("a", "") {
+		t.Error("field a was inserted twice")
+	}
+	if got, want := def.Group("G").Fields(), nameList("start a after_a b end"); !slices.Equal(got, want) {
+		t.Errorf("group fields = %v, want %v", got, want)
+	}
+}
+
+func TestCopyFieldGroups(t *testing.T) {
+	source := mustParseDef(t, `{"name": "f", "groups": [
+		{"name": "G1", "fields": ["a", "b", "c"]},
+		{"name": "G2", "fields": ["x", "b"], "expanded": true},
+		{"name": "G3", "fields": ["a"]}]}`)
+	target := mustParseDef(t, `{"name": "f", "groups": [{"name": "G1", "fields": ["a", "c"]}]}`)
+	groups, created := CopyFieldGroups(target, source, "b")
+	if want := nameList("G1 G2"); !slices.Equal(groups, want) {
+		t.Errorf("groups = %v, want %v", groups, want)
+	}
+	if want := nameList("G2"); !slices.Equal(created, want) {
+		t.Errorf("created = %v, want %v", created, want)
+	}
+	if got, want := target.Group("G1").Fields(), nameList("a b c"); !slices.Equal(got, want) {
+		t.Errorf("existing group fields = %v, want %v", got, want)
+	}
+	group := target.Group("G2")
+	if got, want := group.Fields(), nameList("b"); !slices.Equal(got, want) {
+		t.Errorf("created group fields = %v, want %v", got, want)
+	}
+	if !group.Bool("expanded") {
+		t.Error("created group is not a copy of the source group")
+	}
+	if got, want := source.Group("G2").Fields(), nameList("x b"); !slices.Equal(got, want) {
+		t.Errorf("source group fields = %v, want unchanged %v", got, want)
+	}
+	if target.Group("G3") != nil {
+		t.Error("group without the field was copied")
+	}
+	if groups, created = CopyFieldGroups(target, source, "b"); groups != nil || created != nil {
+		t.Errorf("second copy added field to %v, created %v", groups, created)
+	}
+}
@@ -0,0 +1,254 @@
+package superobject
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	KindBoolean      = "boolean"
+	KindInteger      = "integer"
+	KindDouble       = "double"
+	KindNumeric      = "numeric"
+	KindString       = "string"
+	KindDate         = "date"
+	KindTimestamp    = "timestamp"
+	KindImage        = "image"
+	KindLink         = "link"
+	KindReference    = "reference"
+	KindReferenceSet = "reference_set"
+	KindForeignKey   = "foreign_key"
+	KindPoint        = "point"
+	KindLinestring   = "linestring"
+	KindPolygon      = "polygon"
+	KindRaster       = "raster"
+)
+
+var (
+	// type string split into kind and optional parameters, e.g. "numeric(10,2)"
+	fieldTypePattern   = regexp.MustCompile(`^\s*([a-z_]+)\s*(?:\(([^()]*)\))?\s*$`)
+	featureNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.]*$`)
+	// kinds which may have a target feature as parameter
+	targetKinds = []string{KindReference, KindReferenceSet, KindForeignKey, KindLink}
+	knownKinds  = []string{
+		KindBoolean, KindInteger, KindDouble, KindNumeric, KindString, KindDate, KindTimestamp,
+		KindImage, KindLink, KindReference, KindReferenceSet, KindForeignKey,
+		KindPoint, KindLinestring, KindPolygon, KindRaster,
+	}
+)
+
+// FieldType is a parsed field type string, e.g. "string(100)" or "reference(eo_cable)"
+type FieldType struct {
+	// Kind is the base type, e.g. "string", "numeric", "reference"
+	Kind string
+	// Length is the max length of a string or the total number of digits of a numeric. 0 means unbounded.
+	Length int
+	// Precision is the number of decimal digits of a numeric
+	Precision int
+	// Target is the feature referenced by reference, reference_set, link and foreign_key types
+	Target string
+}
+
+// ParseFieldType parses the type string of a field.
+// Returns error wrapping ErrUnknownType if the kind of the type is not one of Kind* constants.
+func ParseFieldType(s string) (t FieldType, err error) {
+	match := fieldTypePattern.FindStringSubmatch(s)
+	if match == nil {
+		err = fmt.Errorf("malformed type %q", s)
+		return
+	}
+	t.Kind = match[1]
+	params := strings.TrimSpace(match[2])
+	if !slices.Contains(knownKinds, t.Kind) {
+		err = fmt.Errorf("%w %q", ErrUnknownType, s)
+		return
+	}
+	switch {
+	case params == "":
+		if t.Kind == KindForeignKey {
+			err = fmt.Errorf("type %q requires target feature", s)
+		}
+	case t.Kind == KindString:
+		if t.Length, err = parseTypeInt(params); err != nil {
+			err = fmt.Errorf("malformed length of type %q", s)
+		}
+	case t.Kind == KindNumeric:
+		precision, scale, hasScale := strings.Cut(params, ",")
+		if t.Length, err = parseTypeInt(precision); err == nil && hasScale {
+			t.Precision, err = parseTypeInt(scale)
+		}
+		if err != nil || t.Precision > t.Length {
+			err = fmt.Errorf("malformed precision of type %q", s)
+		}
+	case slices.Contains(targetKinds, t.Kind):
+		if !featureNamePattern.MatchString(params) {
+			err = fmt.Errorf("malformed target feature of type %q", s)
+		}
+		t.Target = params
+	default:
+		err = fmt.Errorf("type %q does not accept parameters", s)
+	}
+	return
+}
+
+func parseTypeInt(s string) (int, error) {
+	value, err := strconv.Atoi(strings.TrimSpace(s))
+	if err == nil && value <= 0 {
+		err = fmt.Errorf("value must be positive")
+	}
+	return value, err
+}
+
+// String returns the type in .def file notation
+func (t FieldType) String() string {
+	switch {
+	case t.Target != "":
+		return fmt.Sprintf("%s(%s)", t.Kind, t.Target)
+	case t.Kind == KindNumeric && t.Length > 0 && t.Precision > 0:
+		return fmt.Sprintf("%s(%d,%d)", t.Kind, t.Length, t.Precision)
+	case t.Length > 0:
+		return fmt.Sprintf("%s(%d)", t.Kind, t.Length)
+	}
+	return t.Kind
+}
+
+// IsGeometry returns true for point, linestring and polygon types
+func (t FieldType) IsGeometry() bool {
+	return t.Kind == KindPoint || t.Kind == KindLinestring || t.Kind == KindPolygon
+}
+
+// TypeChange says how values are affected when field type is changed
+type TypeChange int
+
+const (
+	// TypeChangeSafe - types are the same, values are not affected
+	TypeChangeSafe TypeChange = iota
+	// TypeChangeWidening - all values of the old type fit in the new type
+	TypeChangeWidening
+	// TypeChangeLossy - values may be truncated, rounded or can not be converted
+	TypeChangeLossy
+)
+
+func (c TypeChange) String() string {
+	switch c {
+	case TypeChangeSafe:
+		return "safe"
+	case TypeChangeWidening:
+		return "widening"
+	}
+	return "lossy"
+}
+
+// MarshalText implements encoding.TextMarshaler, so reports show the name of the change
+func (c TypeChange) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// CompareTypes returns the effect of changing field type from one type to another
+func CompareTypes(from, to FieldType) TypeChange {
+	if from == to {
+		return TypeChangeSafe
+	}
+	switch to.Kind {
+	case KindString:
+		// every scalar value has text representation
+		if from.Kind == KindString {
+			return lengthChange(from.Length, to.Length)
+		}
+		if to.Length == 0 && !from.IsGeometry() && from.Kind != KindReferenceSet && from.Kind != KindImage {
+			return TypeChangeWidening
+		}
+		if length := textLength(from); length > 0 && to.Length >= length {
+			return TypeChangeWidening
+		}
+	case KindInteger:
+		if from.Kind == KindBoolean {
+			return TypeChangeWidening
+		}
+	case KindDouble:
+		if from.Kind == KindInteger || from.Kind == KindBoolean {
+			return TypeChangeWidening
+		}
+	case KindNumeric:
+		switch from.Kind {
+		case KindNumeric:
+			if to.Length == 0 {
+				return TypeChangeWidening
+			}
+			if from.Length > 0 && to.Precision >= from.Precision &&
+				to.Length-to.Precision >= from.Length-from.Precision {
+				return TypeChangeWidening
+			}
+		case KindInteger, KindBoolean:
+			// 64 bit integer has up to 19 digits
+			if to.Length == 0 || to.Length-to.Precision >= 19 {
+				return TypeChangeWidening
+			}
+		}
+	case KindTimestamp:
+		if from.Kind == KindDate {
+			return TypeChangeWidening
+		}
+	case KindReference, KindReferenceSet, KindLink, KindForeignKey:
+		if from.Kind == to.Kind && to.Target == "" {
+			return TypeChangeWidening
+		}
+	}
+	return TypeChangeLossy
+}
+
+// textLength returns the max length of text representation of values of the type, 0 if it is not bounded
+func textLength(t FieldType) int {
+	switch t.Kind {
+	case KindBoolean:
+		// "false"
+		return 5
+	case KindInteger:
+		// "-9223372036854775808"
+		return 20
+	case KindDouble:
+		// "-1.7976931348623157e+308"
+		return 24
+	case KindNumeric:
+		if t.Length == 0 {
+			return 0
+		}
+		// sign, digits, decimal point and zero before it when all digits are decimal, e.g. "-0.25"
+		length := t.Length + 1
+		if t.Precision > 0 {
+			length++
+		}
+		if t.Precision == t.Length {
+			length++
+		}
+		return length
+	}
+	return 0
+}
+
+func lengthChange(from, to int) TypeChange {
+	switch {
+	case from == to:
+		return TypeChangeSafe
+	case to == 0 || (from != 0 && to > from):
+		return TypeChangeWidening
+	}
+	return TypeChangeLossy
+}
+
+// CompareTypeStrings returns the effect of changing field type given as type strings.
+// Types which can not be parsed are the same only if strings are equal.
+func CompareTypeStrings(from, to string) TypeChange {
+	fromType, err1 := ParseFieldType(from)
+	toType, err2 := ParseFieldType(to)
+	if err1 != nil || err2 != nil {
+		if strings.TrimSpace(from) == strings.TrimSpace(to) {
+			return TypeChangeSafe
+		}
+		return TypeChangeLossy
+	}
+	return CompareTypes(fromType, toType)
+}
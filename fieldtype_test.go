@@ -0,0 +1,77 @@
+package superobject
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompareTypeStrings(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     TypeChange
+	}{
+		{"string(10)", "string(10)", TypeChangeSafe},
+		{"string(10)", "string(20)", TypeChangeWidening},
+		{"string(20)", "string(10)", TypeChangeLossy},
+		{"string(10)", "string", TypeChangeWidening},
+		{"integer", "string", TypeChangeWidening},
+		{"integer", "string(20)", TypeChangeWidening},
+		{"integer", "string(19)", TypeChangeLossy},
+		{"double", "string(24)", TypeChangeWidening},
+		{"double", "string(20)", TypeChangeLossy},
+		{"boolean", "string(5)", TypeChangeWidening},
+		{"boolean", "string(4)", TypeChangeLossy},
+		{"numeric(10,2)", "string(12)", TypeChangeWidening},
+		{"numeric(10,2)", "string(11)", TypeChangeLossy},
+		{"numeric(2,2)", "string(5)", TypeChangeWidening},
+		{"numeric(2,2)", "string(4)", TypeChangeLossy},
+		{"numeric", "string(100)", TypeChangeLossy},
+		{"date", "string(100)", TypeChangeLossy},
+		{"point", "string", TypeChangeLossy},
+		{"integer", "double", TypeChangeWidening},
+		{"double", "integer", TypeChangeLossy},
+		{"integer", "numeric(19)", TypeChangeWidening},
+		{"integer", "numeric(18)", TypeChangeLossy},
+		{"numeric(10,2)", "numeric(12,3)", TypeChangeWidening},
+		{"numeric(10,2)", "numeric(10,3)", TypeChangeLossy},
+		{"date", "timestamp", TypeChangeWidening},
+		{"reference(a)", "reference", TypeChangeWidening},
+		{"reference(a)", "reference(b)", TypeChangeLossy},
+		{"custom", "custom", TypeChangeSafe},
+		{"custom", "string", TypeChangeLossy},
+	}
+	for _, tt := range tests {
+		if got := CompareTypeStrings(tt.from, tt.to); got != tt.want {
+			t.Errorf("CompareTypeStrings(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestParseFieldType(t *testing.T) {
+	tests := []struct {
+		s    string
+		want FieldType
+		err  error
+	}{
+		{s: "integer", want: FieldType{Kind: KindInteger}},
+		{s: "string(100)", want: FieldType{Kind: KindString, Length: 100}},
+		{s: " numeric( 10 , 2 ) ", want: FieldType{Kind: KindNumeric, Length: 10, Precision: 2}},
+		{s: "reference(eo_cable)", want: FieldType{Kind: KindReference, Target: "eo_cable"}},
+		{s: "geometry", err: ErrUnknownType},
+		{s: "string(x)"},
+		{s: "numeric(2,3)"},
+		{s: "foreign_key"},
+		{s: "integer(4)"},
+	}
+	for _, tt := range tests {
+		got, err := ParseFieldType(tt.s)
+		switch {
+		case tt.want.Kind == "" && err == nil:
+			t.Errorf("ParseFieldType(%q) = %+v, want error", tt.s, got)
+		case tt.want.Kind != "" && (err != nil || got != tt.want):
+			t.Errorf("ParseFieldType(%q) = %+v, %v, want %+v", tt.s, got, err, tt.want)
+		case tt.err != nil && !errors.Is(err, tt.err):
+			t.Errorf("ParseFieldType(%q) error = %v, want %v", tt.s, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package superobject
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/kpawlik/om"
+)
+
+const (
+	// FormatIndent is the indentation used by Format
+	FormatIndent = "    "
+)
+
+var (
+	// FieldKeyOrder is the canonical order of keys in field definitions.
+	// Keys not in the list are written after these, in their original order.
+	FieldKeyOrder = []string{
+		"name", "external_name", "type", "value", "key", "generator", "mandatory", "indexed", "read_only",
+		"default", "enum", "unit", "display_unit", "unit_scale", "display_format", "min_value", "max_value",
+		"visible", "viewer_class", "editor_class", "validators",
+	}
+)
+
+// Format returns the feature definition as JSON in canonical style:
+// indentation with FormatIndent, no escaping of HTML characters,
+// keys of field definitions in FieldKeyOrder and a new line at the end.
+// The definition is not modified.
+func Format(def *FeatureDef) (res []byte, err error) {
+	var compact bytes.Buffer
+	formatted := def.Clone()
+	for _, field := range formatted.Fields() {
+		sortKeys(field.m, FieldKeyOrder)
+	}
+	if err = encodeValue(&compact, formatted.m); err != nil {
+		err = fmt.Errorf("failed to format feature definition: %w", err)
+		return
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, compact.Len()*2))
+	if err = json.Indent(buf, compact.Bytes(), "", FormatIndent); err != nil {
+		err = fmt.Errorf("failed to format feature definition: %w", err)
+		return
+	}
+	buf.WriteByte('\n')
+	res = buf.Bytes()
+	return
+}
+
+// FormatSource parses and formats JSON of a feature definition
+func FormatSource(data []byte) ([]byte, error) {
+	def, err := ParseFeatureDef(data)
+	if err != nil {
+		return nil, err
+	}
+	return Format(def)
+}
+
+// MarshalValue returns compact JSON of a value of a definition, without escaping of HTML characters
+func MarshalValue(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeValue(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// sortKeys orders keys of the map, keys from order go first
+func sortKeys(m *om.OrderedMap, order []string) {
+	keys := make([]string, 0, len(m.Keys))
+	for _, key := range order {
+		if _, ok := m.Map[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range m.Keys {
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	m.Keys = keys
+}
+
+// encodeValue writes compact JSON of a value parsed into an ordered map
+func encodeValue(buf *bytes.Buffer, value any) (err error) {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case string:
+		err = encodeString(buf, v)
+	case json.Number:
+		buf.WriteString(v.String())
+	case *om.OrderedMap:
+		buf.WriteByte('{')
+		for i, key := range v.Keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err = encodeString(buf, key); err != nil {
+				return
+			}
+			buf.WriteByte(':')
+			if err = encodeValue(buf, v.Map[key]); err != nil {
+				return
+			}
+		}
+		buf.WriteByte('}')
+	case []any:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err = encodeValue(buf, item); err != nil {
+				return
+			}
+		}
+		buf.WriteByte(']')
+	case []string:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = item
+		}
+		err = encodeValue(buf, list)
+	default:
+		var data []byte
+		if data, err = json.Marshal(v); err == nil {
+			buf.Write(data)
+		}
+	}
+	return
+}
+
+func encodeString(buf *bytes.Buffer, s string) error {
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(s); err != nil {
+		return err
+	}
+	// encoder adds a new line after the value
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
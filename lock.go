@@ -0,0 +1,197 @@
+package superobject
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// LockVersion is the version of the lock file format written by this package
+const LockVersion = 1
+
+// Lock records which component definitions a composed super object was built from.
+// It is stored next to the composed definition, see LockPath.
+type Lock struct {
+	Version    int             `json:"version"`
+	Feature    string          `json:"feature"`
+	Components []ComponentLock `json:"components"`
+	// Path is the file the lock was read from or written to
+	Path string `json:"-"`
+}
+
+// ComponentLock records one component of the super object
+type ComponentLock struct {
+	Name string `json:"name"`
+	// Path of the component definition, relative to the dir of the lock file when possible
+	Path string `json:"path"`
+	// Hash of the component definition file, see FileHash
+	Hash string `json:"hash"`
+	// Fields of the component taken to the super object
+	Fields []string `json:"fields"`
+	// Rules used to select component fields
+	Rules []string `json:"rules"`
+}
+
+// LockPath returns path of the lock file of the composed definition
+func LockPath(defPath string) string {
+	return defPath + ".lock.json"
+}
+
+// ReadLock reads the lock file
+func ReadLock(path string) (lock *Lock, err error) {
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		err = fmt.Errorf("failed to read lock file: %w", err)
+		return
+	}
+	lock = &Lock{}
+	if err = json.Unmarshal(data, lock); err != nil {
+		err = fmt.Errorf("failed to unmarshal lock file %s: %w", path, err)
+		return
+	}
+	if lock.Version > LockVersion {
+		err = fmt.Errorf("%s: unsupported lock file version %d", path, lock.Version)
+		return
+	}
+	lock.Path = path
+	return
+}
+
+// ReadLockOrNew reads the lock file, or returns an empty lock for the feature if the file does not exist
+func ReadLockOrNew(path string, feature string) (lock *Lock, err error) {
+	lock, err = ReadLock(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Lock{Version: LockVersion, Feature: feature, Path: path}, nil
+	}
+	return
+}
+
+// Update records components of the compose report. Existing records of the same components are replaced.
+func (l *Lock) Update(report *ComposeReport) {
+	l.Version = LockVersion
+	l.Feature = report.Feature
+	for _, component := range report.Components {
+		componentLock := ComponentLock{
+			Name:   component.Component,
+			Path:   l.relPath(component.Path),
+			Hash:   component.Hash,
+			Fields: []string{},
+			Rules:  component.Rules,
+		}
+		for _, field := range component.Fields {
+			if field.Action != FieldRemoved && field.Action != FieldStale {
+				componentLock.Fields = append(componentLock.Fields, field.Source)
+			}
+		}
+		if i := slices.IndexFunc(l.Components, func(c ComponentLock) bool { return c.Name == component.Component }); i >= 0 {
+			l.Components[i] = componentLock
+		} else {
+			l.Components = append(l.Components, componentLock)
+		}
+	}
+}
+
+// Remove removes record of the component. Returns false if the lock has no such component.
+func (l *Lock) Remove(component string) bool {
+	n := len(l.Components)
+	l.Components = slices.DeleteFunc(l.Components, func(c ComponentLock) bool { return c.Name == component })
+	return len(l.Components) != n
+}
+
+// Write writes the lock to its Path with WriteFileAtomic
+func (l *Lock) Write(opts WriteOptions) (err error) {
+	var data []byte
+	if data, err = json.MarshalIndent(l, "", FormatIndent); err != nil {
+		return fmt.Errorf("failed to marshal lock file: %w", err)
+	}
+	return WriteFileAtomic(l.Path, append(data, '\n'), opts)
+}
+
+// relPath returns path relative to the dir of the lock file, or absolute path if it is not possible
+func (l *Lock) relPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	dir, err := filepath.Abs(filepath.Dir(l.Path))
+	if err != nil {
+		return abs
+	}
+	if rel, err := filepath.Rel(dir, abs); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return abs
+}
+
+// SetPath sets path of the lock file, relative component paths are changed to stay valid
+func (l *Lock) SetPath(path string) {
+	for i, component := range l.Components {
+		if componentPath := l.ComponentPath(component); componentPath != "" {
+			l.Components[i].Path = componentPath
+		}
+	}
+	l.Path = path
+	for i, component := range l.Components {
+		l.Components[i].Path = l.relPath(component.Path)
+	}
+}
+
+// ComponentPath returns path of the component definition resolved against the dir of the lock file
+func (l *Lock) ComponentPath(component ComponentLock) string {
+	path := filepath.FromSlash(component.Path)
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(l.Path), path)
+}
+
+// ComponentStatus is the result of verification of one component of the lock
+type ComponentStatus struct {
+	Component string `json:"component"`
+	Path      string `json:"path"`
+	// Changed is true if the component definition has different content than when the super object was built
+	Changed bool   `json:"changed"`
+	Hash    string `json:"hash"`
+	// Removed are fields taken to the super object which no longer exist in the component
+	Removed []string `json:"removed,omitempty"`
+	// Added are component fields which would be taken by the same rules but are not in the super object
+	Added []string `json:"added,omitempty"`
+}
+
+// Verify compares current component definitions with the ones the super object was built from.
+// Components which can not be read are recorded in diag.
+func (l *Lock) Verify(diag *Diagnostics) (statuses []ComponentStatus) {
+	for _, component := range l.Components {
+		path := l.ComponentPath(component)
+		def, err := ReadDefFile(path)
+		if diag.Error(err) {
+			continue
+		}
+		status := ComponentStatus{Component: component.Name, Path: path, Hash: def.Hash, Changed: def.Hash != component.Hash}
+		if status.Changed {
+			rules, err := ParseRuleSet(component.Rules)
+			if diag.Error(err) {
+				continue
+			}
+			for _, name := range component.Fields {
+				if !def.HasField(name) {
+					status.Removed = append(status.Removed, name)
+				}
+			}
+			for _, field := range def.Fields() {
+				if included, _ := rules.Match(field); included && !slices.Contains(component.Fields, field.Name()) {
+					status.Added = append(status.Added, field.Name())
+				}
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return
+}
@@ -0,0 +1,89 @@
+package superobject
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLock(t *testing.T) {
+	dir := t.TempDir()
+	componentPath := filepath.Join(dir, "components", "eo_cable.def")
+	if err := os.Mkdir(filepath.Dir(componentPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, filepath.Dir(componentPath), map[string]string{"eo_cable.def": `{"name": "eo_cable", "external_name": "Cable", "fields": [
+		{"name": "a", "type": "string(10)"},
+		{"name": "b", "type": "string(10)"}]}`})
+	component, err := ReadDefFile(componentPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, report, err := NewComposer().Compose(mustParseDef(t, `{"name": "so_cable", "fields": []}`), component)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock, err := ReadLockOrNew(LockPath(filepath.Join(dir, "so_cable.def")), "so_cable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock.Update(report)
+	if err = lock.Write(WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadLock(lock.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, lock) {
+		t.Errorf("read lock %+v, want %+v", read, lock)
+	}
+	want := ComponentLock{Name: "eo_cable", Path: "components/eo_cable.def", Hash: component.Hash, Fields: []string{"a", "b"}, Rules: report.Components[0].Rules}
+	if len(read.Components) != 1 || !reflect.DeepEqual(read.Components[0], want) {
+		t.Fatalf("components = %+v, want [%+v]", read.Components, want)
+	}
+
+	var diag Diagnostics
+	statuses := read.Verify(&diag)
+	wantStatus := ComponentStatus{Component: "eo_cable", Path: componentPath, Hash: component.Hash}
+	if diag.HasErrors() || !reflect.DeepEqual(statuses, []ComponentStatus{wantStatus}) {
+		t.Errorf("unchanged component: statuses %+v (%v), want %+v", statuses, diag.Items(), wantStatus)
+	}
+
+	changed := `{"name": "eo_cable", "external_name": "Cable", "fields": [
+		{"name": "b", "type": "string(10)"},
+		{"name": "c", "type": "string(10)"}]}`
+	writeFiles(t, filepath.Dir(componentPath), map[string]string{"eo_cable.def": changed})
+	statuses = read.Verify(&diag)
+	wantStatus = ComponentStatus{
+		Component: "eo_cable",
+		Path:      componentPath,
+		Changed:   true,
+		Hash:      FileHash([]byte(changed)),
+		Removed:   []string{"a"},
+		Added:     []string{"c"},
+	}
+	if diag.HasErrors() || !reflect.DeepEqual(statuses, []ComponentStatus{wantStatus}) {
+		t.Errorf("changed component: statuses %+v (%v), want %+v", statuses, diag.Items(), wantStatus)
+	}
+
+	if err = os.Remove(componentPath); err != nil {
+		t.Fatal(err)
+	}
+	if statuses = read.Verify(&diag); len(statuses) != 0 || !diag.HasErrors() {
+		t.Errorf("removed component: statuses %+v, diagnostics %v, want no statuses and an error", statuses, diag.Items())
+	}
+}
+
+func TestLockSetPath(t *testing.T) {
+	lock := &Lock{Path: filepath.Join("a", "so.def.lock.json"), Components: []ComponentLock{{Name: "c", Path: "defs/c.def"}}}
+	lock.SetPath(filepath.Join("a", "b", "so.def.lock.json"))
+	if got, want := lock.Components[0].Path, "../defs/c.def"; got != want {
+		t.Errorf("path = %s, want %s", got, want)
+	}
+	if got, want := lock.ComponentPath(lock.Components[0]), filepath.Join("a", "defs", "c.def"); got != want {
+		t.Errorf("component path = %s, want %s", got, want)
+	}
+}
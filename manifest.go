@@ -0,0 +1,164 @@
+package superobject
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Manifest lists super objects and the components they are composed from
+type Manifest struct {
+	SuperObjects []SuperObjectSpec `json:"super_objects"`
+	// Path is the file the manifest was read from
+	Path string `json:"-"`
+}
+
+// SuperObjectSpec describes one super object
+type SuperObjectSpec struct {
+	// Name of the super object feature
+	Name string `json:"name"`
+	// Description is free text, e.g. display name of the super object
+	Description string `json:"description,omitempty"`
+	// Components in the order they are added to the super object
+	Components []ComponentSpec `json:"components"`
+}
+
+// ComponentSpec describes one component of a super object and options used to add its fields.
+// In the manifest component can be given as a name only: "eo_cable".
+type ComponentSpec struct {
+	// Name of the component feature
+	Name string `json:"name"`
+	// Group is the name of the group with component fields. Default is external name of the component
+	Group string `json:"group,omitempty"`
+	// ExcludedTypes of component fields. Default is DefaultExcludedFields
+	ExcludedTypes []string `json:"excluded_types,omitempty"`
+	// Rules include or exclude component fields, e.g. "exclude name:created_*"
+	Rules RuleSet `json:"rules,omitempty"`
+	// CalcAttributes copied from component fields to calculated fields. Default is DefaultCalcAttributes
+	CalcAttributes []string `json:"calc_attributes,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, component can be a name or an object
+func (c *ComponentSpec) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*c = ComponentSpec{Name: name}
+		return nil
+	}
+	type componentSpec ComponentSpec
+	return json.Unmarshal(data, (*componentSpec)(c))
+}
+
+// ReadManifest reads and checks the manifest file
+func ReadManifest(path string) (manifest *Manifest, err error) {
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		err = fmt.Errorf("failed to read manifest: %w", err)
+		return
+	}
+	manifest = &Manifest{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(manifest); err != nil {
+		err = fmt.Errorf("failed to unmarshal manifest %s: %w", path, err)
+		return
+	}
+	manifest.Path = path
+	if err = manifest.check(); err != nil {
+		err = fmt.Errorf("%s: %w", path, err)
+	}
+	return
+}
+
+func (m *Manifest) check() error {
+	names := map[string]bool{}
+	for i, spec := range m.SuperObjects {
+		if spec.Name == "" {
+			return fmt.Errorf("super_objects[%d]: missing name", i)
+		}
+		if names[spec.Name] {
+			return fmt.Errorf("super object %s: defined more than once", spec.Name)
+		}
+		names[spec.Name] = true
+		if len(spec.Components) == 0 {
+			return fmt.Errorf("super object %s: no components", spec.Name)
+		}
+		for j, component := range spec.Components {
+			if component.Name == "" {
+				return fmt.Errorf("super object %s: components[%d]: missing name", spec.Name, j)
+			}
+		}
+	}
+	return nil
+}
+
+// SuperObject returns the super object with the given name or nil
+func (m *Manifest) SuperObject(name string) *SuperObjectSpec {
+	for i := range m.SuperObjects {
+		if m.SuperObjects[i].Name == name {
+			return &m.SuperObjects[i]
+		}
+	}
+	return nil
+}
+
+// Features returns names of all super objects and components in the manifest, without duplicates
+func (m *Manifest) Features() (names []string) {
+	add := func(name string) {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	for _, spec := range m.SuperObjects {
+		add(spec.Name)
+		for _, component := range spec.Components {
+			add(component.Name)
+		}
+	}
+	return
+}
+
+// BuildSuperObject composes the super object from definitions stored in defsDir as <feature>.def files.
+// Component options from the spec override options of the composer.
+func BuildSuperObject(spec SuperObjectSpec, defsDir string, composer *Composer) (def *FeatureDef, report *ComposeReport, err error) {
+	if def, err = ReadDefFile(filepath.Join(defsDir, spec.Name+".def")); err != nil {
+		return
+	}
+	report = &ComposeReport{Feature: def.Name()}
+	for _, component := range spec.Components {
+		var (
+			componentDef    *FeatureDef
+			componentReport *ComposeReport
+		)
+		if componentDef, err = ReadDefFile(filepath.Join(defsDir, component.Name+".def")); err != nil {
+			return
+		}
+		if def, componentReport, err = component.composer(composer).Compose(def, componentDef); err != nil {
+			return
+		}
+		report.DefaultGroupAdded = report.DefaultGroupAdded || componentReport.DefaultGroupAdded
+		report.Components = append(report.Components, componentReport.Components...)
+	}
+	return
+}
+
+// composer returns copy of the composer with component options applied
+func (c ComponentSpec) composer(base *Composer) *Composer {
+	composer := *base
+	if c.Group != "" {
+		composer.GroupName = func(*FeatureDef) string { return c.Group }
+	}
+	if c.ExcludedTypes != nil {
+		composer.ExcludedTypes = c.ExcludedTypes
+	}
+	if c.CalcAttributes != nil {
+		composer.CalcAttributes = c.CalcAttributes
+	}
+	if len(c.Rules) > 0 {
+		composer.Rules = append(append(RuleSet{}, base.Rules...), c.Rules...)
+	}
+	return &composer
+}
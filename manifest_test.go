@@ -0,0 +1,68 @@
+package superobject
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeManifest(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadManifest(t *testing.T) {
+	path := writeManifest(t, `{"super_objects": [{
+		"name": "so_cable",
+		"components": [
+			"eo_cable",
+			{"name": "eo_duct", "group": "Duct", "excluded_types": ["point"]}
+		]
+	}]}`)
+	manifest, err := ReadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Path != path {
+		t.Errorf("path = %s, want %s", manifest.Path, path)
+	}
+	want := []ComponentSpec{
+		{Name: "eo_cable"},
+		{Name: "eo_duct", Group: "Duct", ExcludedTypes: []string{"point"}},
+	}
+	spec := manifest.SuperObject("so_cable")
+	if spec == nil {
+		t.Fatal("super object so_cable not found")
+	}
+	if !reflect.DeepEqual(spec.Components, want) {
+		t.Errorf("components = %+v, want %+v", spec.Components, want)
+	}
+	if got, want := manifest.Features(), []string{"so_cable", "eo_cable", "eo_duct"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("features = %v, want %v", got, want)
+	}
+}
+
+func TestReadManifestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "unknown key", data: `{"super_objects": [{"name": "so", "components": ["a"], "x": 1}]}`},
+		{name: "component of wrong type", data: `{"super_objects": [{"name": "so", "components": [1]}]}`},
+		{name: "missing component name", data: `{"super_objects": [{"name": "so", "components": [{"group": "G"}]}]}`},
+		{name: "no components", data: `{"super_objects": [{"name": "so"}]}`},
+		{name: "duplicate super object", data: `{"super_objects": [{"name": "so", "components": ["a"]}, {"name": "so", "components": ["b"]}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadManifest(writeManifest(t, tt.data)); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package superobject
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kpawlik/om"
+)
+
+var (
+	// placeholder of a field in title, short_description and search templates, e.g. "{name}"
+	placeholderPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+)
+
+const (
+	// templateSeparators is text which may be removed together with a placeholder, e.g. " - " in "{name} - {voltage}"
+	templateSeparators = " \t-–,;:/|"
+	// templateBrackets are pairs of brackets removed together with the placeholder they enclose, e.g. "({voltage})"
+	templateBrackets = "()[]<>"
+)
+
+// Reference is a use of a field in a section of a feature definition
+type Reference struct {
+	// Section is the top-level key of the definition, e.g. "groups", "searches", "title" or "fields"
+	Section string `json:"section"`
+	// Item is the name or value of the list item, empty for title and short_description
+	Item string `json:"item,omitempty"`
+	// Attribute of the item with the reference, e.g. "fields", "value" or "filter"
+	Attribute string `json:"attribute,omitempty"`
+	// Old is the text with the reference, New is the rewritten text.
+	// New is empty when the reference or the whole item was removed.
+	Old string `json:"old"`
+	New string `json:"new,omitempty"`
+	// Removed is true when the whole item was removed, e.g. a search of the field only
+	Removed bool `json:"removed,omitempty"`
+	// Reason why the reference can not be rewritten safely, empty for rewritten references
+	Reason string `json:"reason,omitempty"`
+}
+
+// Location returns the location of the reference, e.g. "title" or "groups[Algemeen].fields"
+func (r Reference) Location() string {
+	location := r.Section
+	if r.Item != "" {
+		location += "[" + r.Item + "]"
+	}
+	if r.Attribute != "" {
+		location += "." + r.Attribute
+	}
+	return location
+}
+
+func (r Reference) String() string {
+	switch {
+	case r.Reason != "":
+		return fmt.Sprintf("%s %q: %s", r.Location(), r.Old, r.Reason)
+	case r.Removed:
+		return fmt.Sprintf("%s: removed", r.Location())
+	case r.New == "":
+		return fmt.Sprintf("%s: removed %q", r.Location(), r.Old)
+	}
+	return fmt.Sprintf("%s: %q -> %q", r.Location(), r.Old, r.New)
+}
+
+// FieldRemoval lists references rewritten by RemoveFieldCascade
+type FieldRemoval struct {
+	Field      string      `json:"field"`
+	References []Reference `json:"references"`
+}
+
+// RemoveFieldCascade removes the field and all references to it:
+//   - the field is removed from fields lists of groups
+//   - "{name}" placeholders are removed from title, short_description and search templates together with
+//     separators around them, e.g. "{name} - {voltage}" becomes "{name}" and "{name} ({voltage})" becomes "{name}"
+//   - searches with placeholders of the field only are removed
+//
+// References which can not be rewritten safely block the removal: filter expressions of queries and filters,
+// values "method(name)" of other fields, templates with other text next to the placeholder or without other
+// placeholders. Then *BlockingReferenceError is returned and the definition is not changed.
+// Returns *FieldNotFoundError if the field does not exist.
+func (d *FeatureDef) RemoveFieldCascade(name string) (removal *FieldRemoval, err error) {
+	if !d.HasField(name) {
+		return nil, &FieldNotFoundError{Path: d.Path, Feature: d.Name(), Field: name}
+	}
+	def := d.Clone()
+	removal = &FieldRemoval{Field: name}
+	var blocking []Reference
+	add := func(ref Reference) {
+		if ref.Reason != "" {
+			blocking = append(blocking, ref)
+		} else {
+			removal.References = append(removal.References, ref)
+		}
+	}
+	for _, group := range def.Groups() {
+		if group.RemoveField(name) {
+			add(Reference{Section: "groups", Item: group.Name(), Attribute: "fields", Old: name})
+		}
+	}
+	for _, section := range []string{"title", "short_description"} {
+		if template, ok := def.Get(section).(string); ok && hasPlaceholder(template, name) {
+			rewritten, reason := removePlaceholder(template, name)
+			add(Reference{Section: section, Old: template, New: rewritten, Reason: reason})
+			if reason == "" {
+				def.Set(section, rewritten)
+			}
+		}
+	}
+	if def.Has("searches") {
+		searches := []any{}
+		for _, item := range def.list("searches") {
+			search, ok := item.(*om.OrderedMap)
+			if !ok {
+				searches = append(searches, item)
+				continue
+			}
+			value, _ := search.Map["value"].(string)
+			if onlyPlaceholder(value, name) {
+				add(Reference{Section: "searches", Item: value, Old: value, Removed: true})
+				continue
+			}
+			for _, attribute := range []string{"value", "description"} {
+				if template, ok := search.Map[attribute].(string); ok && hasPlaceholder(template, name) {
+					rewritten, reason := removePlaceholder(template, name)
+					add(Reference{Section: "searches", Item: value, Attribute: attribute, Old: template, New: rewritten, Reason: reason})
+					if reason == "" {
+						search.Set(attribute, rewritten)
+					}
+				}
+			}
+			searches = append(searches, search)
+		}
+		def.Set("searches", searches)
+	}
+	filterReference := filterReferencePattern(name)
+	for _, section := range []struct{ name, key, attribute string }{{"queries", "value", "filter"}, {"filters", "name", "value"}} {
+		for _, item := range def.list(section.name) {
+			m, ok := item.(*om.OrderedMap)
+			if !ok {
+				continue
+			}
+			if expression, ok := m.Map[section.attribute].(string); ok && filterReference.MatchString(expression) {
+				item, _ := m.Map[section.key].(string)
+				add(Reference{Section: section.name, Item: item, Attribute: section.attribute, Old: expression,
+					Reason: "filter expression can not be rewritten"})
+			}
+		}
+	}
+	for _, field := range def.Fields() {
+		if method, ok := field.MethodName(); ok && method == name && field.Name() != name {
+			add(Reference{Section: "fields", Item: field.Name(), Attribute: "value", Old: field.Value(),
+				Reason: "calculated field uses method of the field"})
+		}
+	}
+	if len(blocking) > 0 {
+		return nil, &BlockingReferenceError{Path: d.Path, Feature: d.Name(), Field: name, References: blocking}
+	}
+	if err = def.RemoveField(name); err != nil {
+		return nil, err
+	}
+	d.Object = def.Object
+	return removal, nil
+}
+
+// hasPlaceholder returns true if the template has placeholder of the field
+func hasPlaceholder(template string, name string) bool {
+	for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		if strings.TrimSpace(match[1]) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyPlaceholder returns true if the template has placeholders and all of them are of the field
+func onlyPlaceholder(template string, name string) bool {
+	matches := placeholderPattern.FindAllStringSubmatch(template, -1)
+	for _, match := range matches {
+		if strings.TrimSpace(match[1]) != name {
+			return false
+		}
+	}
+	return len(matches) > 0
+}
+
+// removePlaceholder removes placeholders of the field from the template with separators between the placeholder
+// and its neighbour. Returns reason why it can not be done safely, empty on success.
+func removePlaceholder(template string, name string) (string, string) {
+	for {
+		locs := placeholderPattern.FindAllStringSubmatchIndex(template, -1)
+		k := -1
+		for i, loc := range locs {
+			if strings.TrimSpace(template[loc[2]:loc[3]]) == name {
+				k = i
+				break
+			}
+		}
+		if k < 0 {
+			return template, ""
+		}
+		if len(locs) == 1 {
+			return template, "template has no other placeholders"
+		}
+		start, end := locs[k][0], locs[k][1]
+		beforeStart, afterEnd := 0, len(template)
+		if k > 0 {
+			beforeStart = locs[k-1][1]
+		}
+		if k < len(locs)-1 {
+			afterEnd = locs[k+1][0]
+		}
+		before, after := template[beforeStart:start], template[end:afterEnd]
+		if i := strings.IndexByte(templateBrackets, lastByte(before)); i >= 0 && i%2 == 0 &&
+			after != "" && after[0] == templateBrackets[i+1] {
+			start--
+			end++
+			before, after = before[:len(before)-1], after[1:]
+		}
+		if strings.Trim(before, templateSeparators) != "" || strings.Trim(after, templateSeparators) != "" {
+			return template, "text next to the placeholder can not be removed safely"
+		}
+		if k > 0 {
+			template = template[:beforeStart] + template[end:]
+		} else {
+			template = template[:start] + template[afterEnd:]
+		}
+	}
+}
+
+func lastByte(s string) byte {
+	if s == "" {
+		return 0
+	}
+	return s[len(s)-1]
+}
+
+// filterReferencePattern returns pattern of the reference to the field in filter expressions, "[name]"
+func filterReferencePattern(name string) *regexp.Regexp {
+	return regexp.MustCompile(`\[\s*` + regexp.QuoteMeta(name) + `\s*\]`)
+}
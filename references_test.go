@@ -0,0 +1,119 @@
+package superobject
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRemovePlaceholder(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+		blocked  bool
+	}{
+		{template: "{name} - {voltage}", want: "{name}"},
+		{template: "{voltage} - {name}", want: "{name}"},
+		{template: "{name} ({voltage})", want: "{name}"},
+		{template: "{name} [{ voltage }]", want: "{name}"},
+		{template: "{name}, {voltage}, {id}", want: "{name}, {id}"},
+		{template: "{voltage}{name}", want: "{name}"},
+		{template: "{name} {voltage} {voltage}", want: "{name}"},
+		{template: "{name}", want: "{name}"},
+		{template: "{voltage}", want: "{voltage}", blocked: true},
+		{template: "{name} {voltage} kV", want: "{name} {voltage} kV", blocked: true},
+		{template: "Cable {voltage} {name}", want: "Cable {voltage} {name}", blocked: true},
+	}
+	for _, tt := range tests {
+		got, reason := removePlaceholder(tt.template, "voltage")
+		if got != tt.want || (reason != "") != tt.blocked {
+			t.Errorf("removePlaceholder(%q) = %q, %q, want %q, blocked %v", tt.template, got, reason, tt.want, tt.blocked)
+		}
+	}
+}
+
+func TestRemoveFieldCascade(t *testing.T) {
+	const fields = `"fields": [{"name": "name", "type": "string"}, {"name": "voltage", "type": "double"}]`
+	tests := []struct {
+		name string
+		def  string
+		// want is the definition after removal, empty when the removal is blocked
+		want       string
+		references int
+	}{
+		{
+			name:       "title and short description",
+			def:        `{"name": "f", ` + fields + `, "title": "{name} - {voltage}", "short_description": "{name} ({voltage})"}`,
+			want:       `{"name": "f", "fields": [{"name": "name", "type": "string"}], "title": "{name}", "short_description": "{name}"}`,
+			references: 2,
+		},
+		{
+			name: "searches and groups",
+			def: `{"name": "f", ` + fields + `, "groups": [{"name": "G", "fields": ["name", "voltage"]}],
+				"searches": [{"value": "{voltage}", "description": "{voltage} kV"}, {"value": "{name} {voltage}", "description": "{name}: {voltage}"}]}`,
+			want: `{"name": "f", "fields": [{"name": "name", "type": "string"}], "groups": [{"name": "G", "fields": ["name"]}],
+				"searches": [{"value": "{name}", "description": "{name}"}]}`,
+			references: 4,
+		},
+		{
+			name: "text next to placeholder",
+			def:  `{"name": "f", ` + fields + `, "title": "{name} {voltage} kV"}`,
+		},
+		{
+			name: "query filter",
+			def:  `{"name": "f", ` + fields + `, "queries": [{"value": "High", "filter": "[voltage] > 10"}]}`,
+		},
+		{
+			name: "filter",
+			def:  `{"name": "f", ` + fields + `, "filters": [{"name": "High", "value": "[ voltage ] > 10"}]}`,
+		},
+		{
+			name: "calculated field",
+			def: `{"name": "f", "fields": [{"name": "name", "type": "string"}, {"name": "voltage", "type": "double"},
+				{"name": "kv", "type": "string", "value": "method(voltage)"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := mustParseDef(t, tt.def)
+			removal, err := def.RemoveFieldCascade("voltage")
+			if tt.want == "" {
+				var blocking *BlockingReferenceError
+				if !errors.As(err, &blocking) || !errors.Is(err, ErrBlockingReference) {
+					t.Fatalf("error = %v, want *BlockingReferenceError", err)
+				}
+				if len(blocking.References) == 0 || blocking.References[0].Reason == "" {
+					t.Errorf("blocking references without reason: %v", blocking.References)
+				}
+				if !def.HasField("voltage") || defJSON(t, def) != defJSON(t, mustParseDef(t, tt.def)) {
+					t.Errorf("definition changed by blocked removal")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(removal.References) != tt.references {
+				t.Errorf("references = %v, want %d", removal.References, tt.references)
+			}
+			if got, want := defJSON(t, def), defJSON(t, mustParseDef(t, tt.want)); got != want {
+				t.Errorf("definition = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestRemoveFieldCascadeNotFound(t *testing.T) {
+	def := mustParseDef(t, `{"name": "f", "fields": []}`)
+	if _, err := def.RemoveFieldCascade("voltage"); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("error = %v, want ErrFieldNotFound", err)
+	}
+}
+
+func defJSON(t *testing.T, def *FeatureDef) string {
+	t.Helper()
+	data, err := def.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,178 @@
+package superobject
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// RuleAction says what happens with a field matched by a rule
+type RuleAction string
+
+const (
+	RuleInclude RuleAction = "include"
+	RuleExclude RuleAction = "exclude"
+)
+
+// Matchers of field rules
+const (
+	// MatchName matches field name with glob pattern, e.g. "created_*"
+	MatchName = "name"
+	// MatchRegex matches field name with regular expression, e.g. "^audit_"
+	MatchRegex = "regex"
+	// MatchKind matches kind of the field type, e.g. "reference" matches "reference(eo_cable)"
+	MatchKind = "kind"
+	// MatchAttr matches field attribute: "internal" when attribute is set and not false, "internal=true" by value
+	MatchAttr = "attr"
+)
+
+// FieldRule includes or excludes component fields. Rules are written as
+// "<action> <matcher>:<pattern>", e.g. "exclude name:created_*" or "include attr:internal=false".
+type FieldRule struct {
+	Action  RuleAction
+	Matcher string
+	Pattern string
+	regex   *regexp.Regexp
+}
+
+// ParseFieldRule parses the rule from "<action> <matcher>:<pattern>" form
+func ParseFieldRule(s string) (rule FieldRule, err error) {
+	action, matcher, ok := strings.Cut(strings.TrimSpace(s), " ")
+	if !ok {
+		err = fmt.Errorf("malformed rule %q, expected \"<action> <matcher>:<pattern>\"", s)
+		return
+	}
+	rule.Action = RuleAction(action)
+	if rule.Action != RuleInclude && rule.Action != RuleExclude {
+		err = fmt.Errorf("unknown action of rule %q, expected %s or %s", s, RuleInclude, RuleExclude)
+		return
+	}
+	if rule.Matcher, rule.Pattern, ok = strings.Cut(strings.TrimSpace(matcher), ":"); !ok || rule.Pattern == "" {
+		err = fmt.Errorf("malformed rule %q, expected \"<action> <matcher>:<pattern>\"", s)
+		return
+	}
+	switch rule.Matcher {
+	case MatchName:
+		if _, err = path.Match(rule.Pattern, ""); err != nil {
+			err = fmt.Errorf("malformed pattern of rule %q: %w", s, err)
+		}
+	case MatchRegex:
+		if rule.regex, err = regexp.Compile(rule.Pattern); err != nil {
+			err = fmt.Errorf("malformed pattern of rule %q: %w", s, err)
+		}
+	case MatchKind, MatchAttr:
+	default:
+		err = fmt.Errorf("unknown matcher of rule %q, expected %s, %s, %s or %s", s, MatchName, MatchRegex, MatchKind, MatchAttr)
+	}
+	return
+}
+
+func (r FieldRule) String() string {
+	return fmt.Sprintf("%s %s:%s", r.Action, r.Matcher, r.Pattern)
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (r FieldRule) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (r *FieldRule) UnmarshalText(text []byte) (err error) {
+	*r, err = ParseFieldRule(string(text))
+	return
+}
+
+// Matches returns true if the rule matches the field
+func (r FieldRule) Matches(field *FieldDef) bool {
+	switch r.Matcher {
+	case MatchName:
+		matched, _ := path.Match(r.Pattern, field.Name())
+		return matched
+	case MatchRegex:
+		regex := r.regex
+		if regex == nil {
+			var err error
+			if regex, err = regexp.Compile(r.Pattern); err != nil {
+				return false
+			}
+		}
+		return regex.MatchString(field.Name())
+	case MatchKind:
+		if field.Type() == r.Pattern {
+			return true
+		}
+		fieldType, err := ParseFieldType(field.Type())
+		return err == nil && fieldType.Kind == r.Pattern
+	case MatchAttr:
+		key, value, hasValue := strings.Cut(r.Pattern, "=")
+		if !field.Has(key) {
+			return false
+		}
+		attr := attrString(field.Get(key))
+		if hasValue {
+			return attr == value
+		}
+		return attr != "false" && attr != "null" && attr != ""
+	}
+	return false
+}
+
+// attrString returns string representation of an attribute value
+func attrString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return "null"
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// RuleSet is an ordered list of rules. The last rule matching a field decides
+// if the field is included, fields not matched by any rule are included.
+type RuleSet []FieldRule
+
+// ParseRuleSet parses list of rules
+func ParseRuleSet(rules []string) (ruleSet RuleSet, err error) {
+	for _, s := range rules {
+		var rule FieldRule
+		if rule, err = ParseFieldRule(s); err != nil {
+			return
+		}
+		ruleSet = append(ruleSet, rule)
+	}
+	return
+}
+
+// TypeRules returns rules excluding fields of the given type kinds
+func TypeRules(excludedTypes []string) (ruleSet RuleSet) {
+	for _, kind := range excludedTypes {
+		ruleSet = append(ruleSet, FieldRule{Action: RuleExclude, Matcher: MatchKind, Pattern: kind})
+	}
+	return
+}
+
+// SystemFieldsRule excludes myWorld system fields
+var SystemFieldsRule = FieldRule{Action: RuleExclude, Matcher: MatchName, Pattern: "myw_*"}
+
+// Match returns true if the field is included and the rule which decided it.
+// Rule is nil when no rule matches the field.
+func (rs RuleSet) Match(field *FieldDef) (included bool, rule *FieldRule) {
+	included = true
+	for i := range rs {
+		if rs[i].Matches(field) {
+			rule = &rs[i]
+			included = rule.Action == RuleInclude
+		}
+	}
+	return
+}
+
+// ExcludedField is a field excluded by a rule
+type ExcludedField struct {
+	Name string `json:"name"`
+	Rule string `json:"rule"`
+}
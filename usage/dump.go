@@ -0,0 +1,255 @@
+// Package usage finds where fields are used in the myWorld configuration of a database,
+// read offline from a PostgreSQL plain-text dump, or generates SQL to check it in the database.
+package usage
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrMalformedDump is returned when a COPY block of the dump can not be parsed
+var ErrMalformedDump = errors.New("malformed dump")
+
+// Table is the data of one COPY block of the dump
+type Table struct {
+	// Name is the table name with schema, e.g. "myw.dd_field"
+	Name    string
+	Columns []string
+	// Rows are values of columns, NULL values are empty
+	Rows [][]string
+}
+
+// Column returns index of the first of the columns the table has, -1 if it has none of them
+func (t *Table) Column(names ...string) int {
+	for _, name := range names {
+		if i := slices.Index(t.Columns, name); i >= 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// Dump is the data of tables read from a PostgreSQL plain-text dump
+type Dump struct {
+	Path   string
+	Tables map[string]*Table
+}
+
+// Table returns the table by name, with or without schema on either side, nil if the dump has no data of the table
+func (d *Dump) Table(name string) *Table {
+	if table, ok := d.Tables[name]; ok {
+		return table
+	}
+	for tableName, table := range d.Tables {
+		if sameTable(name, tableName) || sameTable(tableName, name) {
+			return table
+		}
+	}
+	return nil
+}
+
+// ReadDumpFile reads the dump file, see ReadDump
+func ReadDumpFile(path string, tables ...string) (dump *Dump, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dump: %w", err)
+	}
+	defer file.Close()
+	if dump, err = ReadDump(file, tables...); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	dump.Path = path
+	return
+}
+
+// ReadDump reads data of COPY ... FROM stdin blocks of a plain-text dump written by pg_dump.
+// Only the given tables are read, all tables if none is given. Other SQL statements are skipped.
+func ReadDump(r io.Reader, tables ...string) (dump *Dump, err error) {
+	dump = &Dump{Tables: map[string]*Table{}}
+	reader := bufio.NewReader(r)
+	var (
+		table  *Table
+		lineNo int
+	)
+	for {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, fmt.Errorf("failed to read dump: %w", readErr)
+		}
+		if line == "" && readErr == io.EOF {
+			break
+		}
+		lineNo++
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		switch {
+		case table != nil && line == `\.`:
+			table = nil
+		case table != nil:
+			values := strings.Split(line, "\t")
+			if len(values) != len(table.Columns) {
+				return nil, fmt.Errorf("line %d: %w: %s: expected %d values, got %d", lineNo, ErrMalformedDump, table.Name, len(table.Columns), len(values))
+			}
+			for i, value := range values {
+				if values[i], err = unescapeCopy(value); err != nil {
+					return nil, fmt.Errorf("line %d: %w: %s: %v", lineNo, ErrMalformedDump, table.Name, err)
+				}
+			}
+			table.Rows = append(table.Rows, values)
+		case strings.HasPrefix(line, "COPY "):
+			copyTable, err := parseCopy(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w: %v", lineNo, ErrMalformedDump, err)
+			}
+			if len(tables) > 0 && !slices.ContainsFunc(tables, func(name string) bool { return sameTable(name, copyTable.Name) }) {
+				// data of other tables is skipped
+				if err = skipCopy(reader, &lineNo); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if existing, ok := dump.Tables[copyTable.Name]; ok && slices.Equal(existing.Columns, copyTable.Columns) {
+				copyTable = existing
+			}
+			dump.Tables[copyTable.Name] = copyTable
+			table = copyTable
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+	if table != nil {
+		return nil, fmt.Errorf("%w: %s: missing end of COPY data", ErrMalformedDump, table.Name)
+	}
+	return dump, nil
+}
+
+// skipCopy reads lines until the end of COPY data
+func skipCopy(reader *bufio.Reader, lineNo *int) error {
+	for {
+		line, err := reader.ReadString('\n')
+		*lineNo++
+		if strings.TrimRight(line, "\r\n") == `\.` {
+			return nil
+		}
+		if err == io.EOF {
+			return fmt.Errorf("%w: missing end of COPY data", ErrMalformedDump)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read dump: %w", err)
+		}
+	}
+}
+
+// sameTable returns true if name is the table name, name may be without schema
+func sameTable(name string, tableName string) bool {
+	if name == tableName {
+		return true
+	}
+	_, unqualified, ok := strings.Cut(tableName, ".")
+	return ok && !strings.Contains(name, ".") && unqualified == name
+}
+
+// parseCopy parses statement "COPY schema.table (column, ...) FROM stdin;"
+func parseCopy(line string) (*Table, error) {
+	statement := strings.TrimSpace(strings.TrimPrefix(line, "COPY "))
+	open := strings.IndexByte(statement, '(')
+	closing := strings.LastIndexByte(statement, ')')
+	if open < 0 || closing < open || !strings.HasPrefix(strings.TrimSpace(statement[closing+1:]), "FROM stdin") {
+		return nil, fmt.Errorf("unsupported COPY statement %q, expected COPY table (columns) FROM stdin", line)
+	}
+	table := &Table{Name: unquoteIdentifier(strings.TrimSpace(statement[:open]))}
+	for _, column := range strings.Split(statement[open+1:closing], ",") {
+		table.Columns = append(table.Columns, unquoteIdentifier(strings.TrimSpace(column)))
+	}
+	return table, nil
+}
+
+// unquoteIdentifier removes quotes of parts of the identifier, e.g. "myw"."default" is myw.default
+func unquoteIdentifier(identifier string) string {
+	var (
+		buf      strings.Builder
+		inQuotes bool
+	)
+	for i := 0; i < len(identifier); i++ {
+		c := identifier[i]
+		switch {
+		case c == '"' && inQuotes && i+1 < len(identifier) && identifier[i+1] == '"':
+			buf.WriteByte('"')
+			i++
+		case c == '"':
+			inQuotes = !inQuotes
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// unescapeCopy decodes a value of COPY text format, \N is NULL and is returned as empty string
+func unescapeCopy(value string) (string, error) {
+	if value == `\N` {
+		return "", nil
+	}
+	if !strings.Contains(value, `\`) {
+		return value, nil
+	}
+	var buf strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != '\\' {
+			buf.WriteByte(c)
+			continue
+		}
+		i++
+		if i >= len(value) {
+			return "", errors.New("backslash at the end of value")
+		}
+		switch c = value[i]; c {
+		case 'b':
+			buf.WriteByte('\b')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case 't':
+			buf.WriteByte('\t')
+		case 'v':
+			buf.WriteByte('\v')
+		case 'x':
+			end := i + 1
+			for end < len(value) && end < i+3 && isHex(value[end]) {
+				end++
+			}
+			if end == i+1 {
+				buf.WriteByte(c)
+				continue
+			}
+			n, _ := strconv.ParseUint(value[i+1:end], 16, 8)
+			buf.WriteByte(byte(n))
+			i = end - 1
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			end := i
+			for end < len(value) && end < i+3 && value[end] >= '0' && value[end] <= '7' {
+				end++
+			}
+			n, _ := strconv.ParseUint(value[i:end], 8, 8)
+			buf.WriteByte(byte(n))
+			i = end - 1
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String(), nil
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
@@ -0,0 +1,115 @@
+package usage
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnescapeCopy(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		err   bool
+	}{
+		{value: `plain`, want: "plain"},
+		{value: `\N`, want: ""},
+		{value: `\\N`, want: `\N`},
+		{value: `a\tb`, want: "a\tb"},
+		{value: `line\nnext\r`, want: "line\nnext\r"},
+		{value: `\b\f\v`, want: "\b\f\v"},
+		{value: `C:\\dir\\file`, want: `C:\dir\file`},
+		{value: `\101\0\12`, want: "A\x00\n"},
+		{value: `\1011`, want: "A1"},
+		{value: `\x41\x4a4\xg`, want: "AJ4xg"},
+		{value: `\{name\}`, want: "{name}"},
+		{value: `end\`, err: true},
+	}
+	for _, tt := range tests {
+		got, err := unescapeCopy(tt.value)
+		if got != tt.want || (err != nil) != tt.err {
+			t.Errorf("unescapeCopy(%q) = %q, %v, want %q, error %v", tt.value, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestUnquoteIdentifier(t *testing.T) {
+	tests := []struct {
+		identifier string
+		want       string
+	}{
+		{`myw.dd_field`, "myw.dd_field"},
+		{`"myw"."default"`, "myw.default"},
+		{`"say ""hi"""`, `say "hi"`},
+	}
+	for _, tt := range tests {
+		if got := unquoteIdentifier(tt.identifier); got != tt.want {
+			t.Errorf("unquoteIdentifier(%q) = %q, want %q", tt.identifier, got, tt.want)
+		}
+	}
+}
+
+func TestReadDump(t *testing.T) {
+	const dump = "SET statement_timeout = 0;\n" +
+		"COPY myw.dd_layer (id, name) FROM stdin;\n" +
+		"1\tCables\n" +
+		"\\.\n" +
+		"COPY \"myw\".\"dd_field\" (table_name, internal_name, value) FROM stdin;\n" +
+		"eo_cable\tname\t\\N\n" +
+		"eo_cable\tlabel\tmethod(name)\\tx\n" +
+		"\\.\n"
+	tests := []struct {
+		name   string
+		dump   string
+		tables []string
+		want   map[string]*Table
+		err    error
+	}{
+		{
+			name: "all tables",
+			dump: dump,
+			want: map[string]*Table{
+				"myw.dd_layer": {Name: "myw.dd_layer", Columns: []string{"id", "name"}, Rows: [][]string{{"1", "Cables"}}},
+				"myw.dd_field": {Name: "myw.dd_field", Columns: []string{"table_name", "internal_name", "value"},
+					Rows: [][]string{{"eo_cable", "name", ""}, {"eo_cable", "label", "method(name)\tx"}}},
+			},
+		},
+		{
+			name:   "selected table without schema",
+			dump:   dump,
+			tables: []string{"dd_field"},
+			want: map[string]*Table{
+				"myw.dd_field": {Name: "myw.dd_field", Columns: []string{"table_name", "internal_name", "value"},
+					Rows: [][]string{{"eo_cable", "name", ""}, {"eo_cable", "label", "method(name)\tx"}}},
+			},
+		},
+		{
+			name: "wrong number of values",
+			dump: "COPY myw.dd_layer (id, name) FROM stdin;\n1\n\\.\n",
+			err:  ErrMalformedDump,
+		},
+		{
+			name: "missing end of data",
+			dump: "COPY myw.dd_layer (id, name) FROM stdin;\n1\tCables\n",
+			err:  ErrMalformedDump,
+		},
+		{
+			name:   "missing end of skipped data",
+			dump:   "COPY myw.dd_layer (id, name) FROM stdin;\n1\tCables\n",
+			tables: []string{"myw.dd_field"},
+			err:    ErrMalformedDump,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadDump(strings.NewReader(tt.dump), tt.tables...)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if err == nil && !reflect.DeepEqual(got.Tables, tt.want) {
+				t.Errorf("tables = %+v, want %+v", got.Tables, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package usage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultSchema is the schema of myWorld configuration tables
+const DefaultSchema = "myw"
+
+// QuoteIdentifier returns the identifier quoted for PostgreSQL, double quotes in the name are doubled
+func QuoteIdentifier(name string) string {
+	name = strings.ReplaceAll(name, "\x00", "")
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteLiteral returns the string quoted as PostgreSQL literal, single quotes in the string are doubled.
+// Strings with backslashes are written as E'...' literals, so they are read the same
+// regardless of standard_conforming_strings.
+func QuoteLiteral(value string) string {
+	value = strings.ReplaceAll(value, "\x00", "")
+	value = strings.ReplaceAll(value, `'`, `''`)
+	if strings.Contains(value, `\`) {
+		return `E'` + strings.ReplaceAll(value, `\`, `\\`) + `'`
+	}
+	return `'` + value + `'`
+}
+
+// UsedFieldsSQL returns one query which lists removed fields used in field groups of their features.
+// fields are names of removed fields of each feature, they are passed to the query as a VALUES list,
+// so all values are quoted literals and the query can be run with psql -f. Features are sorted by name.
+func UsedFieldsSQL(schema string, fields map[string][]string) string {
+	if schema == "" {
+		schema = DefaultSchema
+	}
+	features := make([]string, 0, len(fields))
+	for feature := range fields {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+	var rows []string
+	for _, feature := range features {
+		for _, field := range fields[feature] {
+			rows = append(rows, fmt.Sprintf("        (%s, %s)", QuoteLiteral(feature), QuoteLiteral(field)))
+		}
+	}
+	var buf strings.Builder
+	buf.WriteString("WITH removed_fields (feature_name, field_name) AS (\n")
+	if len(rows) == 0 {
+		buf.WriteString("    SELECT NULL::text, NULL::text WHERE false\n")
+	} else {
+		buf.WriteString("    VALUES\n")
+		buf.WriteString(strings.Join(rows, ",\n"))
+		buf.WriteString("\n")
+	}
+	buf.WriteString(")\n")
+	fmt.Fprintf(&buf, `SELECT rf.feature_name, fg.display_name, rf.field_name
+FROM removed_fields rf
+JOIN %[1]s.%[2]s fg ON fg.feature_name = rf.feature_name
+JOIN %[1]s.%[3]s fgi ON fgi.container_id = fg.id AND fgi.field_name = rf.field_name
+ORDER BY rf.feature_name, rf.field_name, fg.display_name;
+`, QuoteIdentifier(schema), QuoteIdentifier("dd_field_group"), QuoteIdentifier("dd_field_group_item"))
+	return buf.String()
+}
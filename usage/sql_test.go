@@ -0,0 +1,60 @@
+package usage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"dd_field", `"dd_field"`},
+		{`say "hi"`, `"say ""hi"""`},
+		{`back\slash`, `"back\slash"`},
+		{"nul\x00", `"nul"`},
+		{"", `""`},
+	}
+	for _, tt := range tests {
+		if got := QuoteIdentifier(tt.name); got != tt.want {
+			t.Errorf("QuoteIdentifier(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteLiteral(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"eo_cable", `'eo_cable'`},
+		{"it's", `'it''s'`},
+		{`back\slash`, `E'back\\slash'`},
+		{`it's \n`, `E'it''s \\n'`},
+		{`\'`, `E'\\'''`},
+		{"nul\x00", `'nul'`},
+		{"", `''`},
+	}
+	for _, tt := range tests {
+		if got := QuoteLiteral(tt.value); got != tt.want {
+			t.Errorf("QuoteLiteral(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestUsedFieldsSQL(t *testing.T) {
+	query := UsedFieldsSQL("", map[string][]string{"eo_pole": {"height"}, "eo_cable": {"it's", `a\b`}})
+	for _, want := range []string{
+		"        ('eo_cable', 'it''s'),\n        ('eo_cable', E'a\\\\b'),\n        ('eo_pole', 'height')\n",
+		`JOIN "myw"."dd_field_group" fg`,
+		`JOIN "myw"."dd_field_group_item" fgi`,
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("query does not contain %q:\n%s", want, query)
+		}
+	}
+	if empty := UsedFieldsSQL("other", nil); !strings.Contains(empty, "WHERE false") || !strings.Contains(empty, `"other"."dd_field_group"`) {
+		t.Errorf("query without fields:\n%s", empty)
+	}
+}
@@ -0,0 +1,236 @@
+package usage
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+)
+
+// Tables are the myWorld configuration tables read from the dump
+var Tables = []string{"myw.dd_field", "myw.dd_field_group", "myw.dd_field_group_item", "myw.dd_query", "myw.dd_layer"}
+
+// Kind is where a field is used
+type Kind string
+
+const (
+	// KindField - the field is still defined in myw.dd_field
+	KindField Kind = "field"
+	// KindValue - value of other field of the feature refers to the field, e.g. "method(name)"
+	KindValue Kind = "value"
+	// KindGroup - the field is in a field group of the feature, myw.dd_field_group_item
+	KindGroup Kind = "group"
+	// KindQuery - query of the feature refers to the field, myw.dd_query
+	KindQuery Kind = "query"
+	// KindLayer - layer of the feature refers to the field, myw.dd_layer
+	KindLayer Kind = "layer"
+)
+
+// Usage is one use of a field found in the dump
+type Usage struct {
+	Kind  Kind   `json:"kind"`
+	Table string `json:"table"`
+	// Item identifies the row, e.g. name of the field, display name of the group, the query or the layer
+	Item string `json:"item,omitempty"`
+	// Column and Value which refer to the field
+	Column string `json:"column"`
+	Value  string `json:"value"`
+}
+
+func (u Usage) String() string {
+	return fmt.Sprintf("%s %s %q: %s = %q", u.Kind, u.Table, u.Item, u.Column, u.Value)
+}
+
+// FieldUsage lists usages of one field
+type FieldUsage struct {
+	Feature string  `json:"feature"`
+	Field   string  `json:"field"`
+	Usages  []Usage `json:"usages"`
+}
+
+// Used returns true if the field is used anywhere
+func (u *FieldUsage) Used() bool {
+	return len(u.Usages) > 0
+}
+
+// Report lists usages of fields found in the dump
+type Report struct {
+	Dump string `json:"dump"`
+	// MissingTables are Tables which have no data in the dump, usages in them are not reported
+	MissingTables []string      `json:"missing_tables,omitempty"`
+	Fields        []*FieldUsage `json:"fields"`
+}
+
+// Used returns fields which are used anywhere
+func (r *Report) Used() (used []*FieldUsage) {
+	for _, field := range r.Fields {
+		if field.Used() {
+			used = append(used, field)
+		}
+	}
+	return
+}
+
+// Analyze finds usages of fields in the dump. fields are names of fields of each feature,
+// the report lists them sorted by feature, in the given order within a feature.
+//
+// Rows of myw.dd_field, myw.dd_field_group and myw.dd_query belong to the feature by their feature column.
+// Layers have no such column, a layer row is reported when a column has the feature name and a column
+// refers to the field. A value refers to the field when it is the field name or has "[name]", "{name}",
+// "method(name)" or JSON string value "name".
+func Analyze(dump *Dump, fields map[string][]string) *Report {
+	report := &Report{Dump: dump.Path, Fields: []*FieldUsage{}}
+	for _, name := range Tables {
+		if dump.Table(name) == nil {
+			report.MissingTables = append(report.MissingTables, name)
+		}
+	}
+	features := make([]string, 0, len(fields))
+	for feature := range fields {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+	for _, feature := range features {
+		groups := fieldGroups(dump, feature)
+		for _, field := range fields[feature] {
+			usage := &FieldUsage{Feature: feature, Field: field, Usages: []Usage{}}
+			ref := newReference(field)
+			usage.Usages = append(usage.Usages, fieldUsages(dump, feature, field, ref)...)
+			usage.Usages = append(usage.Usages, groupUsages(dump, groups, field)...)
+			usage.Usages = append(usage.Usages, queryUsages(dump, feature, ref)...)
+			usage.Usages = append(usage.Usages, layerUsages(dump, feature, ref)...)
+			report.Fields = append(report.Fields, usage)
+		}
+	}
+	return report
+}
+
+// reference matches values which refer to the field
+type reference struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+func newReference(name string) *reference {
+	quoted := regexp.QuoteMeta(name)
+	return &reference{
+		name: name,
+		pattern: regexp.MustCompile(`\[\s*` + quoted + `\s*\]|\{\s*` + quoted + `\s*\}|method\(\s*` + quoted + `\s*\)|"` +
+			quoted + `"\s*[,}\]]`),
+	}
+}
+
+func (r *reference) in(value string) bool {
+	return value == r.name || r.pattern.MatchString(value)
+}
+
+// column returns value of the column of the row, empty if the table has no such column
+func column(row []string, i int) string {
+	if i < 0 {
+		return ""
+	}
+	return row[i]
+}
+
+// fieldUsages finds the field definition and values of other fields of the feature in myw.dd_field
+func fieldUsages(dump *Dump, feature, field string, ref *reference) (usages []Usage) {
+	table := dump.Table("myw.dd_field")
+	if table == nil {
+		return
+	}
+	featureColumn := table.Column("table_name", "feature_name")
+	nameColumn := table.Column("internal_name", "name")
+	valueColumn := table.Column("value")
+	for _, row := range table.Rows {
+		if column(row, featureColumn) != feature {
+			continue
+		}
+		name := column(row, nameColumn)
+		switch {
+		case name == field:
+			usages = append(usages, Usage{Kind: KindField, Table: table.Name, Item: name, Column: table.Columns[nameColumn], Value: name})
+		case valueColumn >= 0 && ref.in(row[valueColumn]):
+			usages = append(usages, Usage{Kind: KindValue, Table: table.Name, Item: name, Column: table.Columns[valueColumn], Value: row[valueColumn]})
+		}
+	}
+	return
+}
+
+// fieldGroups returns display names of field groups of the feature by group id
+func fieldGroups(dump *Dump, feature string) map[string]string {
+	groups := map[string]string{}
+	table := dump.Table("myw.dd_field_group")
+	if table == nil {
+		return groups
+	}
+	idColumn := table.Column("id")
+	featureColumn := table.Column("feature_name", "table_name")
+	nameColumn := table.Column("display_name", "name")
+	for _, row := range table.Rows {
+		if idColumn >= 0 && column(row, featureColumn) == feature {
+			groups[row[idColumn]] = column(row, nameColumn)
+		}
+	}
+	return groups
+}
+
+// groupUsages finds the field in items of the field groups
+func groupUsages(dump *Dump, groups map[string]string, field string) (usages []Usage) {
+	table := dump.Table("myw.dd_field_group_item")
+	if table == nil {
+		return
+	}
+	groupColumn := table.Column("container_id", "group_id")
+	fieldColumn := table.Column("field_name")
+	if groupColumn < 0 || fieldColumn < 0 {
+		return
+	}
+	for _, row := range table.Rows {
+		if group, ok := groups[row[groupColumn]]; ok && row[fieldColumn] == field {
+			usages = append(usages, Usage{Kind: KindGroup, Table: table.Name, Item: group, Column: table.Columns[fieldColumn], Value: field})
+		}
+	}
+	return
+}
+
+// queryUsages finds queries of the feature which refer to the field
+func queryUsages(dump *Dump, feature string, ref *reference) (usages []Usage) {
+	table := dump.Table("myw.dd_query")
+	if table == nil {
+		return
+	}
+	featureColumn := table.Column("myw_object_type", "feature_name", "table_name")
+	itemColumn := table.Column("myw_search_val1", "display_value", "id")
+	for _, row := range table.Rows {
+		if featureColumn < 0 || row[featureColumn] != feature {
+			continue
+		}
+		for i, value := range row {
+			if i != featureColumn && ref.in(value) {
+				usages = append(usages, Usage{Kind: KindQuery, Table: table.Name, Item: column(row, itemColumn), Column: table.Columns[i], Value: value})
+			}
+		}
+	}
+	return
+}
+
+// layerUsages finds layers of the feature which refer to the field
+func layerUsages(dump *Dump, feature string, ref *reference) (usages []Usage) {
+	table := dump.Table("myw.dd_layer")
+	if table == nil {
+		return
+	}
+	featureRef := newReference(feature)
+	nameColumn := table.Column("name", "display_name", "id")
+	for _, row := range table.Rows {
+		if !slices.ContainsFunc(row, featureRef.in) {
+			continue
+		}
+		for i, value := range row {
+			if value != feature && ref.in(value) {
+				usages = append(usages, Usage{Kind: KindLayer, Table: table.Name, Item: column(row, nameColumn), Column: table.Columns[i], Value: value})
+			}
+		}
+	}
+	return
+}
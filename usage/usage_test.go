@@ -0,0 +1,93 @@
+package usage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// usageDump has usages of eo_cable fields in all tables, and rows of eo_duct and values
+// which look like references but do not refer to eo_cable fields
+const usageDump = "COPY myw.dd_field (table_name, internal_name, value) FROM stdin;\n" +
+	"eo_cable\tstatus\t\\N\n" +
+	"eo_cable\tlabel\tmethod(status)\n" +
+	"eo_cable\tvoltage\t\\N\n" +
+	"eo_duct\tstatus\t\\N\n" +
+	"eo_duct\tlabel\tmethod(status)\n" +
+	"\\.\n" +
+	"COPY myw.dd_field_group (id, feature_name, display_name) FROM stdin;\n" +
+	"1\teo_cable\tAlgemeen\n" +
+	"2\teo_duct\tDuct\n" +
+	"\\.\n" +
+	"COPY myw.dd_field_group_item (container_id, field_name) FROM stdin;\n" +
+	"1\tstatus\n" +
+	"1\tvoltage\n" +
+	"2\tstatus\n" +
+	"\\.\n" +
+	"COPY myw.dd_query (myw_object_type, myw_search_val1, attrib_query) FROM stdin;\n" +
+	"eo_cable\tPlanned\t[status] = 'planned'\n" +
+	"eo_cable\tKeyed\t{\"status\": 1}\n" +
+	"eo_duct\tDuct planned\t[status] = 'planned'\n" +
+	"\\.\n" +
+	"COPY myw.dd_layer (name, spec) FROM stdin;\n" +
+	"Cables\t{\"feature\": \"eo_cable\", \"style\": \"status\"}\n" +
+	"Ducts\t{\"feature\": \"eo_duct\", \"style\": \"status\"}\n" +
+	"\\.\n"
+
+func TestAnalyze(t *testing.T) {
+	dump, err := ReadDump(strings.NewReader(usageDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := Analyze(dump, map[string][]string{"eo_cable": {"status", "voltage", "unused"}})
+	want := &Report{Fields: []*FieldUsage{
+		{Feature: "eo_cable", Field: "status", Usages: []Usage{
+			{Kind: KindField, Table: "myw.dd_field", Item: "status", Column: "internal_name", Value: "status"},
+			{Kind: KindValue, Table: "myw.dd_field", Item: "label", Column: "value", Value: "method(status)"},
+			{Kind: KindGroup, Table: "myw.dd_field_group_item", Item: "Algemeen", Column: "field_name", Value: "status"},
+			{Kind: KindQuery, Table: "myw.dd_query", Item: "Planned", Column: "attrib_query", Value: "[status] = 'planned'"},
+			{Kind: KindLayer, Table: "myw.dd_layer", Item: "Cables", Column: "spec", Value: `{"feature": "eo_cable", "style": "status"}`},
+		}},
+		{Feature: "eo_cable", Field: "voltage", Usages: []Usage{
+			{Kind: KindField, Table: "myw.dd_field", Item: "voltage", Column: "internal_name", Value: "voltage"},
+			{Kind: KindGroup, Table: "myw.dd_field_group_item", Item: "Algemeen", Column: "field_name", Value: "voltage"},
+		}},
+		{Feature: "eo_cable", Field: "unused", Usages: []Usage{}},
+	}}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("report:\n%s\nwant:\n%s", formatReport(report), formatReport(want))
+	}
+	var used []string
+	for _, field := range report.Used() {
+		used = append(used, field.Field)
+	}
+	if want := []string{"status", "voltage"}; !reflect.DeepEqual(used, want) {
+		t.Errorf("used = %v, want %v", used, want)
+	}
+}
+
+func TestAnalyzeMissingTables(t *testing.T) {
+	dump, err := ReadDump(strings.NewReader(usageDump), "myw.dd_field", "myw.dd_query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := Analyze(dump, map[string][]string{"eo_cable": {"voltage"}})
+	if want := []string{"myw.dd_field_group", "myw.dd_field_group_item", "myw.dd_layer"}; !reflect.DeepEqual(report.MissingTables, want) {
+		t.Errorf("missing tables = %v, want %v", report.MissingTables, want)
+	}
+	want := []Usage{{Kind: KindField, Table: "myw.dd_field", Item: "voltage", Column: "internal_name", Value: "voltage"}}
+	if len(report.Fields) != 1 || !reflect.DeepEqual(report.Fields[0].Usages, want) {
+		t.Errorf("report:\n%s\nwant usages %v", formatReport(report), want)
+	}
+}
+
+func formatReport(report *Report) string {
+	var lines []string
+	for _, field := range report.Fields {
+		lines = append(lines, field.Feature+"."+field.Field+":")
+		for _, usage := range field.Usages {
+			lines = append(lines, "  "+usage.String())
+		}
+	}
+	return strings.Join(lines, "\n")
+}
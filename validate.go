@@ -0,0 +1,133 @@
+package superobject
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kpawlik/om"
+)
+
+// ValidationError describes a single problem found in a feature definition
+type ValidationError struct {
+	// Path of the definition file, empty for definitions created in memory
+	Path string
+	// Feature name
+	Feature string
+	// Field name or position of the field (e.g. "fields[3]"), empty for feature level problems
+	Field   string
+	Message string
+	// Err is the cause of the problem (*FieldNotFoundError, *UnexpectedTypeError), may be nil
+	Err error
+	// Warning is true for problems which do not make the definition invalid, e.g. type
+	// of a kind unknown to this package, which may be supported by a newer myWorld version
+	Warning bool
+}
+
+func (e *ValidationError) Error() string {
+	msg := e.Message
+	if e.Field != "" {
+		msg = fmt.Sprintf("field %s: %s", e.Field, msg)
+	}
+	if e.Feature != "" {
+		msg = fmt.Sprintf("%s: %s", e.Feature, msg)
+	}
+	return withPath(e.Path, msg)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks the feature definition against the myWorld .def rules.
+// All problems are returned, validation does not stop on the first one.
+// Types of unknown kinds are reported as warnings, see ValidationError.Warning.
+func Validate(def *FeatureDef) (errs []*ValidationError) {
+	featureName := def.Name()
+	report := func(field string, cause error, format string, args ...any) {
+		errs = append(errs, &ValidationError{
+			Path:    def.Path,
+			Feature: featureName,
+			Field:   field,
+			Message: fmt.Sprintf(format, args...),
+			Err:     cause,
+		})
+	}
+	warn := func(field string, cause error, format string, args ...any) {
+		report(field, cause, format, args...)
+		errs[len(errs)-1].Warning = true
+	}
+	unexpectedType := func(key string, expected string, got any) error {
+		return &UnexpectedTypeError{Path: def.Path, Key: key, Expected: expected, Got: got}
+	}
+	notFound := func(field string) error {
+		return &FieldNotFoundError{Path: def.Path, Feature: featureName, Field: field}
+	}
+	switch name := def.Get("name").(type) {
+	case nil:
+		report("", nil, "missing name")
+	case string:
+		if name == "" {
+			report("", nil, "missing name")
+		}
+	default:
+		report("", unexpectedType("name", "string", name), "name is not a string")
+	}
+	switch fields := def.Get("fields").(type) {
+	case nil:
+		report("", nil, "missing fields")
+	case []any, []string:
+	default:
+		report("", unexpectedType("fields", "array", fields), "fields is not a list")
+	}
+	names := map[string]bool{}
+	for i, item := range def.list("fields") {
+		key := fmt.Sprintf("fields[%d]", i)
+		field, ok := item.(*om.OrderedMap)
+		if !ok {
+			report(key, unexpectedType(key, "object", item), "field definition is not an object")
+			continue
+		}
+		name, ok := field.Map["name"].(string)
+		if !ok && field.Map["name"] != nil {
+			report(key, unexpectedType(key+".name", "string", field.Map["name"]), "name is not a string")
+			continue
+		}
+		if name == "" {
+			report(key, nil, "missing name")
+			continue
+		}
+		if names[name] {
+			report(name, nil, "duplicate field name")
+		}
+		names[name] = true
+		switch fieldType := field.Map["type"].(type) {
+		case nil:
+			report(name, nil, "missing type")
+		case string:
+			if _, err := ParseFieldType(fieldType); errors.Is(err, ErrUnknownType) {
+				warn(name, err, "%v", err)
+			} else if err != nil {
+				report(name, nil, "%v", err)
+			}
+		default:
+			report(name, unexpectedType(key+".type", "string", fieldType), "type is not a string")
+		}
+	}
+	for _, field := range def.Fields() {
+		if method, ok := field.MethodName(); ok && !names[method] {
+			report(field.Name(), notFound(method), "value %q has no matching field", field.Value())
+		}
+	}
+	for i, group := range def.Groups() {
+		groupName := group.Name()
+		if groupName == "" {
+			groupName = fmt.Sprintf("groups[%d]", i)
+		}
+		for _, name := range group.Fields() {
+			if !names[name] {
+				report(name, notFound(name), "group %s refers to field which does not exist", groupName)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,51 @@
+package superobject
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		def      string
+		errors   int
+		warnings int
+	}{
+		{
+			name: "valid",
+			def: `{"name": "f", "fields": [{"name": "id", "type": "integer"}, {"name": "c", "type": "string", "value": "method(id)"}],
+				"groups": [{"name": "G", "fields": ["id"]}]}`,
+		},
+		{name: "missing name and fields", def: `{}`, errors: 2},
+		{name: "fields is not a list", def: `{"name": "f", "fields": {}}`, errors: 1},
+		{name: "field is not an object", def: `{"name": "f", "fields": ["a"]}`, errors: 1},
+		{name: "missing field name and type", def: `{"name": "f", "fields": [{"type": "integer"}, {"name": "a"}]}`, errors: 2},
+		{name: "malformed type", def: `{"name": "f", "fields": [{"name": "a", "type": "string(10"}]}`, errors: 1},
+		{name: "unknown type", def: `{"name": "f", "fields": [{"name": "a", "type": "geometry(4326)"}]}`, warnings: 1},
+		{name: "method without field", def: `{"name": "f", "fields": [{"name": "a", "type": "string", "value": "method(b)"}]}`, errors: 1},
+		{
+			name:   "duplicate field and unknown group field",
+			def:    `{"name": "f", "fields": [{"name": "a", "type": "integer"}, {"name": "a", "type": "integer"}], "groups": [{"name": "G", "fields": ["b"]}]}`,
+			errors: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errorCount, warningCount int
+			for _, err := range Validate(mustParseDef(t, tt.def)) {
+				if err.Warning {
+					warningCount++
+					if !errors.Is(err, ErrUnknownType) {
+						t.Errorf("warning %v does not wrap ErrUnknownType", err)
+					}
+				} else {
+					errorCount++
+				}
+			}
+			if errorCount != tt.errors || warningCount != tt.warnings {
+				t.Errorf("got %d errors, %d warnings, want %d errors, %d warnings", errorCount, warningCount, tt.errors, tt.warnings)
+			}
+		})
+	}
+}
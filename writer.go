@@ -0,0 +1,199 @@
+package superobject
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrFileChanged is returned when a file changed on disk since it was read
+var ErrFileChanged = errors.New("file changed on disk since it was read")
+
+// BackupMode says if and how a backup of an overwritten file is kept.
+// It implements flag.Value, so it can be used as a command line flag.
+type BackupMode string
+
+const (
+	// BackupNone - no backup
+	BackupNone BackupMode = "none"
+	// BackupBak - previous content is kept in <file>.bak, older backup is replaced
+	BackupBak BackupMode = "bak"
+	// BackupTimestamp - previous content is kept in <file>.<yyyymmddThhmmss>.bak
+	BackupTimestamp BackupMode = "timestamp"
+)
+
+func (m *BackupMode) String() string {
+	if m == nil || *m == "" {
+		return string(BackupNone)
+	}
+	return string(*m)
+}
+
+// Set implements flag.Value
+func (m *BackupMode) Set(value string) error {
+	switch mode := BackupMode(value); mode {
+	case BackupNone, BackupBak, BackupTimestamp:
+		*m = mode
+		return nil
+	}
+	return fmt.Errorf("unknown backup mode %q, expected %s, %s or %s", value, BackupNone, BackupBak, BackupTimestamp)
+}
+
+// WriteOptions control how files are written by WriteFileAtomic and WriteDefFile
+type WriteOptions struct {
+	Backup BackupMode
+	// ExpectedHash is the hash of the file content (see FileHash) when it was read.
+	// Write fails with ErrFileChanged if the file has different content now. Empty skips the check.
+	ExpectedHash string
+}
+
+// FileHash returns hash of the file content in form "sha256:<hex>"
+func FileHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// WriteFileAtomic writes data to a temporary file in the same dir, syncs it and renames it to path,
+// so readers never see partially written file. Mode of existing file is preserved, new files get 0644.
+func WriteFileAtomic(path string, data []byte, opts WriteOptions) (err error) {
+	perm := fs.FileMode(0644)
+	old, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		old = nil
+		if opts.ExpectedHash != "" {
+			return fmt.Errorf("%s: %w: file was removed", path, ErrFileChanged)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	default:
+		if opts.ExpectedHash != "" && FileHash(old) != opts.ExpectedHash {
+			return fmt.Errorf("%s: %w", path, ErrFileChanged)
+		}
+		if info, err := os.Stat(path); err == nil {
+			perm = info.Mode().Perm()
+		}
+	}
+	if old != nil && bytes.Equal(old, data) {
+		return nil
+	}
+	if old != nil {
+		if err = backup(path, old, opts.Backup); err != nil {
+			return
+		}
+	}
+	if err = writeTemp(path, data, perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeTemp writes data to a temporary file and renames it to path
+func writeTemp(path string, data []byte, perm fs.FileMode) (err error) {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		return
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return
+	}
+	// persist the rename, not supported on every platform so errors are ignored
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+func backup(path string, data []byte, mode BackupMode) error {
+	var backupPath string
+	switch mode {
+	case "", BackupNone:
+		return nil
+	case BackupBak:
+		backupPath = path + ".bak"
+	case BackupTimestamp:
+		backupPath = fmt.Sprintf("%s.%s.bak", path, time.Now().Format("20060102T150405"))
+	default:
+		return fmt.Errorf("unknown backup mode %q", mode)
+	}
+	if err := writeTemp(backupPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write backup %s: %w", backupPath, err)
+	}
+	return nil
+}
+
+// WriteDefFile writes the feature definition to the file with WriteFileAtomic.
+// When path is the file the definition was read from, the write fails with ErrFileChanged
+// if the file was changed by someone else in the meantime.
+// After successful write definition Path and Hash are set to the written file.
+func WriteDefFile(path string, def *FeatureDef, opts WriteOptions) (err error) {
+	var buf bytes.Buffer
+	if err = WriteDef(&buf, def); err != nil {
+		return
+	}
+	if opts.ExpectedHash == "" && def.Hash != "" && sameFile(path, def.Path) {
+		opts.ExpectedHash = def.Hash
+	}
+	if err = WriteFileAtomic(path, buf.Bytes(), opts); err != nil {
+		return
+	}
+	def.Path = path
+	def.Hash = FileHash(buf.Bytes())
+	return
+}
+
+func sameFile(path1, path2 string) bool {
+	if path1 == "" || path2 == "" {
+		return false
+	}
+	abs1, err1 := filepath.Abs(path1)
+	abs2, err2 := filepath.Abs(path2)
+	return err1 == nil && err2 == nil && abs1 == abs2
+}
+
+// RemoveFile removes the file, backup of its content is kept according to opts.
+// Removing a file which does not exist is not an error.
+func RemoveFile(path string, opts WriteOptions) (err error) {
+	old, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	case opts.ExpectedHash != "" && FileHash(old) != opts.ExpectedHash:
+		return fmt.Errorf("%s: %w", path, ErrFileChanged)
+	}
+	if err = backup(path, old, opts.Backup); err != nil {
+		return
+	}
+	if err = os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package superobject
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, ".*.tmp-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) > 0 {
+		t.Errorf("temporary files left: %v", matches)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.def")
+	if err := WriteFileAtomic(path, []byte("new"), WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("new file mode = %v (%v), want 0644", info.Mode().Perm(), err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(path, []byte("changed"), WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); got != "changed" {
+		t.Errorf("content = %q, want %q", got, "changed")
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v (%v), want 0600 preserved", info.Mode().Perm(), err)
+	}
+	assertNoTempFiles(t, dir)
+}
+
+func TestWriteFileAtomicBackup(t *testing.T) {
+	tests := []struct {
+		mode    BackupMode
+		pattern string
+	}{
+		{mode: BackupNone},
+		{mode: BackupBak, pattern: `^a\.def\.bak$`},
+		{mode: BackupTimestamp, pattern: `^a\.def\.\d{8}T\d{6}\.bak$`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "a.def")
+			if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := WriteFileAtomic(path, []byte("new"), WriteOptions{Backup: tt.mode}); err != nil {
+				t.Fatal(err)
+			}
+			backups, err := filepath.Glob(filepath.Join(dir, "a.def.*"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.pattern == "" {
+				if len(backups) > 0 {
+					t.Errorf("unexpected backups %v", backups)
+				}
+				return
+			}
+			if len(backups) != 1 {
+				t.Fatalf("got backups %v, want one", backups)
+			}
+			if name := filepath.Base(backups[0]); !regexp.MustCompile(tt.pattern).MatchString(name) {
+				t.Errorf("backup name %s does not match %s", name, tt.pattern)
+			}
+			if got := readFile(t, backups[0]); got != "old" {
+				t.Errorf("backup content = %q, want %q", got, "old")
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomicFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.def")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// backup can not replace non empty directory, so the write fails before the file is replaced
+	if err := os.MkdirAll(filepath.Join(path+".bak", "x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(path, []byte("new"), WriteOptions{Backup: BackupBak}); err == nil {
+		t.Fatal("got no error")
+	}
+	if got := readFile(t, path); got != "old" {
+		t.Errorf("content = %q, want unchanged %q", got, "old")
+	}
+	assertNoTempFiles(t, dir)
+}
+
+func TestWriteFileAtomicChanged(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.def")
+	if err := os.WriteFile(path, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := WriteFileAtomic(path, []byte("new"), WriteOptions{ExpectedHash: FileHash([]byte("old"))})
+	if !errors.Is(err, ErrFileChanged) {
+		t.Errorf("got %v, want ErrFileChanged", err)
+	}
+	err = WriteFileAtomic(filepath.Join(dir, "removed.def"), []byte("new"), WriteOptions{ExpectedHash: FileHash([]byte("old"))})
+	if !errors.Is(err, ErrFileChanged) {
+		t.Errorf("got %v, want ErrFileChanged for removed file", err)
+	}
+	if got := readFile(t, path); got != "changed" {
+		t.Errorf("content = %q, want unchanged %q", got, "changed")
+	}
+}
+
+func TestWriteDefFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.def")
+	if err := os.WriteFile(path, []byte(`{"name": "a", "fields": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	def, err := ReadDefFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def.Set("external_name", "A")
+	if err = WriteDefFile(path, def, WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if def.Hash != FileHash([]byte(readFile(t, path))) {
+		t.Error("hash was not updated after write")
+	}
+	// someone else changes the file, next write of the definition must fail
+	if err = os.WriteFile(path, []byte(`{"name": "b", "fields": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	def.Set("external_name", "B")
+	if err = WriteDefFile(path, def, WriteOptions{}); !errors.Is(err, ErrFileChanged) {
+		t.Errorf("got %v, want ErrFileChanged", err)
+	}
+	// writing to another file does not check the hash
+	other := filepath.Join(dir, "b.def")
+	if err = WriteDefFile(other, def, WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if def.Path != other {
+		t.Errorf("path = %s, want %s", def.Path, other)
+	}
+	assertNoTempFiles(t, dir)
+}
+
+func TestWriteTempCleanup(t *testing.T) {
+	dir := t.TempDir()
+	// rename over non empty directory fails after the temporary file was written
+	target := filepath.Join(dir, "target")
+	if err := os.MkdirAll(filepath.Join(target, "x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTemp(target, []byte("data"), 0644); err == nil {
+		t.Fatal("got no error")
+	}
+	assertNoTempFiles(t, dir)
+}